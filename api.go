@@ -5,6 +5,7 @@
 package gocedar
 
 import (
+	"bytes"
 	"errors"
 )
 
@@ -17,6 +18,10 @@ var (
 	ErrInvalidKey = errors.New("cedar: invalid key")
 	// ErrInvalidVal invalid value error
 	ErrInvalidVal = errors.New("cedar: invalid val")
+	// ErrNotEmpty the mmap path already holds a trie
+	ErrNotEmpty = errors.New("cedar: mmap path is not empty")
+	// ErrNoCounts the counts of the keys are not enabled
+	ErrNoCounts = errors.New("cedar: counts not enabled")
 )
 
 func isReduced(reduced ...bool) bool {
@@ -27,9 +32,25 @@ func isReduced(reduced ...bool) bool {
 	return true
 }
 
-func (cd *Cedar) get(key []byte, from, pos int) *int {
-	to := cd.getNode(key, from, pos)
-	return &cd.array[to].baseV
+// isTerminal reports whether the node `to` is the child labeled 0 of its parent,
+// which holds the value of the key ending at the parent. Its `baseV` is a value
+// of any sign, never a base.
+func (cd *Cedar) isTerminal(to int) bool {
+	from := cd.array[to].check
+	return to > 0 && from >= 0 && cd.array[from].base(cd.Reduced) == to
+}
+
+// setValue stores `val` to the node `to` returned by `getNode`. With Reduced a
+// leaf holds its value in place only if it is not negative, since a negative
+// `baseV` is a base, the others go to a new terminal node under the leaf. It
+// returns the node holding the value.
+func (cd *Cedar) setValue(to, val int) int {
+	if cd.Reduced && val < 0 && !cd.isTerminal(to) {
+		to = cd.follow(to, 0)
+		cd.moveValue(to)
+	}
+	cd.array[to].baseV = val
+	return to
 }
 
 // getNode get the follow node by key, split by update()
@@ -40,6 +61,7 @@ func (cd *Cedar) getNode(key []byte, from, pos int) int {
 			if value >= 0 && value != ValLimit {
 				to := cd.follow(from, 0)
 				cd.array[to].baseV = value
+				cd.moveValue(to)
 			}
 		}
 
@@ -55,7 +77,22 @@ func (cd *Cedar) getNode(key []byte, from, pos int) int {
 }
 
 // Jump jump a node `from` to another node by following the `path`, split by find()
+//
+// With Tail a leaf stands for its whole tail, jumping into the middle of a tail fails.
 func (cd *Cedar) Jump(key []byte, from int) (to int, err error) {
+	return cd.jumpTo(cd.escape(key), from)
+}
+
+// jumpTo is the `Jump` of the escaped keys.
+func (cd *Cedar) jumpTo(key []byte, from int) (to int, err error) {
+	if cd.tail != nil {
+		to, rest, err := cd.jump(key, from)
+		if err == nil && len(rest) > 0 {
+			err = ErrNoKey
+		}
+		return to, err
+	}
+
 	// pos := 0
 	// recursively matching the key.
 	for _, k := range key {
@@ -73,91 +110,245 @@ func (cd *Cedar) Jump(key []byte, from int) (to int, err error) {
 	return to, nil
 }
 
-// Find key from double array trie, with `from` as the cursor to traverse the nodes.
-func (cd *Cedar) Find(key []byte, from int) (int, error) {
-	to, err := cd.Jump(key, from)
-	if cd.Reduced {
-		if cd.array[from].baseV >= 0 {
-			if err == nil && to != 0 {
-				return cd.array[to].baseV, nil
+// jump is Jump aware of the tails: when the key runs into the tail of a leaf,
+// it returns the leaf and the part of the tail left after the key.
+func (cd *Cedar) jump(key []byte, from int) (to int, rest []byte, err error) {
+	if cd.tail == nil {
+		to, err = cd.jumpTo(key, from)
+		return
+	}
+
+	for pos := 0; ; pos++ {
+		if v := cd.array[from].baseV; v >= 0 {
+			if v == ValLimit {
+				return from, nil, ErrNoKey
+			}
+			suffix, _ := cd.tail.entry(v)
+			if !bytes.HasPrefix(suffix, key[pos:]) {
+				return from, nil, ErrNoKey
 			}
-			return 0, ErrNoKey
+			return from, suffix[len(key)-pos:], nil
+		}
+
+		if pos == len(key) {
+			return from, nil, nil
+		}
+
+		to = cd.array[from].base(cd.Reduced) ^ int(key[pos])
+		if cd.array[to].check != from {
+			return from, nil, ErrNoKey
 		}
+		from = to
 	}
+}
 
-	// return the value of the node if `check` is correctly marked fpr the ownership,
-	// otherwise it means no value is stored.
-	n := cd.array[cd.array[to].base(cd.Reduced)]
-	if n.check != to {
+// Find key from double array trie, with `from` as the cursor to traverse the nodes.
+func (cd *Cedar) Find(key []byte, from int) (int, error) {
+	to, err := cd.jumpTo(cd.escape(key), from)
+	if err != nil {
 		return 0, ErrNoKey
 	}
-	return n.baseV, nil
+
+	val, err := cd.Value(to)
+	if err != nil {
+		return 0, ErrNoKey
+	}
+	return val, nil
 }
 
 // Value get the path value
 func (cd *Cedar) Value(path int) (val int, err error) {
-	val = cd.array[path].baseV
-	if val >= 0 {
-		return val, nil
+	to := path
+	if val = cd.array[path].baseV; !cd.isTerminal(path) && (!cd.Reduced || val < 0) {
+		to = cd.array[path].base(cd.Reduced)
+		if to < 0 || cd.array[to].check != path {
+			return 0, ErrNoVal
+		}
+		val = cd.array[to].baseV
 	}
 
-	to := cd.array[path].base(cd.Reduced)
-	if cd.array[to].check == path && cd.array[to].baseV >= 0 {
-		return cd.array[to].baseV, nil
+	if val == ValLimit {
+		return 0, ErrNoVal
 	}
-
-	return 0, ErrNoVal
+	return cd.leafValue(val), nil
 }
 
-// Insert the key for the value on []byte
+// Insert the key for the value on []byte, the value is any int but ValLimit.
 func (cd *Cedar) Insert(key []byte, val int) error {
-	if val < 0 || val >= ValLimit {
+	if val == ValLimit {
 		return ErrInvalidVal
 	}
 
-	p := cd.get(key, 0, 0)
-	*p = val
-
+	cd.keepOrigin(cd.insert(cd.escape(key), val), key)
 	return nil
 }
 
-// Update the key for the value, it is public interface that works on []byte
+// insert is the `Insert` of the escaped keys, it returns the node holding the value.
+func (cd *Cedar) insert(key []byte, val int) int {
+	return cd.insertAt(key, 0, 0, val)
+}
+
+// insertAt is `insert` starting at the node `from` reached by the first `pos`
+// bytes of the key, see `lookup`.
+func (cd *Cedar) insertAt(key []byte, from, pos, val int) int {
+	if cd.tail != nil {
+		to := cd.tailGet(key, from, pos)
+		if cd.tail.value(cd.array[to].baseV) == ValLimit {
+			cd.addCount(to, 1)
+		}
+		cd.tail.setValue(cd.array[to].baseV, val)
+		return to
+	}
+
+	to := cd.getNode(key, from, pos)
+	if cd.array[to].baseV == ValLimit {
+		cd.addCount(to, 1)
+	}
+	return cd.setValue(to, val)
+}
+
+// Update the key for the value, it is public interface that works on []byte.
+// The value is added to the one of the key, a negative value subtracts from it,
+// and the sum must neither overflow nor be ValLimit.
 func (cd *Cedar) Update(key []byte, value int) error {
-	p := cd.get(key, 0, 0)
+	origin := key
+	key = cd.escape(key)
+	if cd.tail != nil {
+		to := cd.tailGet(key, 0, 0)
+		val := cd.tail.value(cd.array[to].baseV)
+		isNew := val == ValLimit
+		if isNew {
+			val = 0
+		}
+		sum, ok := addValue(val, value)
+		if !ok {
+			return ErrInvalidVal
+		}
 
-	if *p == ValLimit && cd.Reduced {
-		*p = value
+		if isNew {
+			cd.addCount(to, 1)
+		}
+		cd.tail.setValue(cd.array[to].baseV, sum)
+		cd.keepOrigin(to, origin)
 		return nil
 	}
 
-	*p += value
+	to := cd.getNode(key, 0, 0)
+	val := cd.array[to].baseV
+	isNew := val == ValLimit
+	if isNew {
+		val = 0
+	}
+	sum, ok := addValue(val, value)
+	if !ok {
+		return ErrInvalidVal
+	}
+
+	if isNew {
+		cd.addCount(to, 1)
+	}
+	cd.keepOrigin(cd.setValue(to, sum), origin)
 	return nil
 }
 
+// addValue returns `val + delta`, and false if the sum overflows or is ValLimit.
+func addValue(val, delta int) (int, bool) {
+	sum := val + delta
+	if delta > 0 && sum < val || delta < 0 && sum > val || sum == ValLimit {
+		return 0, false
+	}
+	return sum, true
+}
+
 // Delete the key from the trie, the internal interface that works on []byte
 func (cd *Cedar) Delete(key []byte) error {
 	// move the cursor to the right place and use erase__ to delete it.
-	to, err := cd.Jump(key, 0)
+	to, err := cd.valueNode(cd.escape(key))
+	if err != nil {
+		return err
+	}
+
+	cd.drop(to)
+	return nil
+}
+
+// drop removes the key whose value is held by the node `to`, with its records.
+func (cd *Cedar) drop(to int) {
+	if cd.tail != nil {
+		cd.tail.free(cd.array[to].baseV)
+	}
+	cd.eachStore(func(p *payloadStore) {
+		p.free(p.ref(to))
+	})
+	cd.addCount(to, -1)
+	cd.erase(to)
+}
+
+// valueNode returns the node holding the value of the key.
+func (cd *Cedar) valueNode(key []byte) (int, error) {
+	to, err := cd.jumpTo(key, 0)
 	if err != nil {
-		return ErrNoKey
+		return 0, ErrNoKey
 	}
+	return cd.holder(to)
+}
 
-	if cd.array[to].baseV < 0 && cd.Reduced {
-		base := cd.array[to].base(cd.Reduced)
-		if cd.array[base].check == to {
-			to = base
+// lookup walks the escaped key without changing the trie. It returns the node
+// holding the value of the key and true if the key is there, otherwise the
+// last node matched and the number of bytes of the key leading to it, from
+// which `insertAt` goes on.
+func (cd *Cedar) lookup(key []byte) (from, pos int, ok bool) {
+	for ; pos < len(key); pos++ {
+		if cd.Reduced && cd.array[from].baseV >= 0 {
+			break
 		}
+
+		to := cd.array[from].base(cd.Reduced) ^ int(key[pos])
+		if cd.array[to].check != from {
+			return from, pos, false
+		}
+		from = to
 	}
 
-	if !cd.Reduced {
-		to = cd.array[to].base(cd.Reduced)
+	// the rest of the key must be the tail of the leaf.
+	if v := cd.array[from].baseV; cd.tail != nil && v >= 0 {
+		if v == ValLimit {
+			return from, pos, false
+		}
+		suffix, val := cd.tail.entry(v)
+		return from, pos, val != ValLimit && bytes.Equal(suffix, key[pos:])
+	}
+	if pos < len(key) {
+		return from, pos, false
 	}
 
-	from := to
+	to, err := cd.holder(from)
+	if err != nil || cd.leafValue(cd.array[to].baseV) == ValLimit {
+		return from, pos, false
+	}
+	return to, pos, true
+}
+
+// holder returns the node holding the value of the key ending at the node `to`,
+// which is `to` itself for a leaf or a terminal node.
+func (cd *Cedar) holder(to int) (int, error) {
+	if cd.isTerminal(to) || (cd.Reduced && cd.array[to].baseV >= 0) {
+		return to, nil
+	}
+
+	// the path is there but the key is not.
+	base := cd.array[to].base(cd.Reduced)
+	if base < 0 || cd.array[base].check != to || cd.array[base].baseV == ValLimit {
+		return 0, ErrNoKey
+	}
+	return base, nil
+}
+
+// erase removes the node `to` holding the value, and the nodes above it
+// left without children.
+func (cd *Cedar) erase(to int) {
 	for to > 0 {
-		if cd.Reduced {
-			from = cd.array[to].check
-		}
+		from := cd.array[to].check
 		base := cd.array[from].base(cd.Reduced)
 		label := byte(to ^ base)
 
@@ -177,13 +368,11 @@ func (cd *Cedar) Delete(key []byte) error {
 			break
 		}
 	}
-
-	return nil
 }
 
 // Get get the key value on []byte
 func (cd *Cedar) Get(key []byte) (value int, err error) {
-	to, err := cd.Jump(key, 0)
+	to, err := cd.jumpTo(cd.escape(key), 0)
 	if err != nil {
 		return 0, err
 	}
@@ -209,40 +398,63 @@ func (cd *Cedar) PrefixMatch(key []byte, n ...int) (ids []int) {
 		num = n[0]
 	}
 
+	cd.prefixMatch(cd.escape(key), func(to, _ int) bool {
+		ids = append(ids, to)
+		num--
+		return num != 0
+	})
+
+	return
+}
+
+// prefixMatch calls fn with the node and the length of every key which is a
+// prefix of the escaped `key`, the shortest first, until fn returns false.
+func (cd *Cedar) prefixMatch(key []byte, fn func(to, end int) bool) {
 	for from, i := 0, 0; i < len(key); i++ {
-		to, err := cd.Jump(key[i:i+1], from)
+		to, rest, err := cd.jump(key[i:i+1], from)
 		if err != nil {
-			break
+			return
 		}
 
-		_, err = cd.Value(to)
-		if err == nil {
-			ids = append(ids, to)
-			num--
-			if num == 0 {
-				return
+		// the leaf matches if the key covers its whole tail, and nothing is below it.
+		if len(rest) > 0 {
+			if bytes.HasPrefix(key[i+1:], rest) {
+				fn(to, i+1+len(rest))
 			}
+			return
 		}
 
+		if _, err = cd.Value(to); err == nil && !fn(to, i+1) {
+			return
+		}
 		from = to
 	}
-
-	return
 }
 
 // PrefixPredict eturn the list of words in the dictionary
-// that has `key` as their prefix
+// that has `key` as their prefix, in the order of the keys
 func (cd *Cedar) PrefixPredict(key []byte, n ...int) (ids []int) {
 	num := 0
 	if len(n) > 0 {
 		num = n[0]
 	}
+	key = cd.escape(key)
 
-	root, err := cd.Jump(key, 0)
+	root, _, err := cd.jump(key, 0)
 	if err != nil {
 		return
 	}
 
+	// the sibling chains of an unordered trie are sorted on the fly.
+	if !cd.ordered {
+		cd.predict(root, func(to int) bool {
+			ids = append(ids, to)
+			num--
+			return num != 0
+		})
+		return
+	}
+
 	for from, err := cd.begin(root); err == nil; from, err = cd.next(from, root) {
 		ids = append(ids, from)
 		num--
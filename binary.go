@@ -0,0 +1,60 @@
+package gocedar
+
+import "bytes"
+
+// escapeByte starts the two bytes standing for the byte 0 and for itself in
+// a binary key: 0x00 is 0x01 0x01 and 0x01 is 0x01 0x02. The escaped keys keep
+// the order and the prefixes of the keys, and never hold the byte 0.
+const escapeByte = 0x01
+
+// escape returns the key as stored in the trie, normalized by the Normalizer,
+// and escaped if Binary is enabled and the key holds a byte to escape.
+func (cd *Cedar) escape(key []byte) []byte {
+	if cd.normalize != nil {
+		key = cd.normalize(key)
+	}
+	if !cd.binary {
+		return key
+	}
+	return escapeKey(key)
+}
+
+// unescape returns the key stored as `key`, it does not modify `key`.
+func (cd *Cedar) unescape(key []byte) []byte {
+	if !cd.binary || bytes.IndexByte(key, escapeByte) < 0 {
+		return key
+	}
+
+	buf := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if key[i] == escapeByte && i+1 < len(key) {
+			i++
+			buf = append(buf, key[i]-1)
+			continue
+		}
+		buf = append(buf, key[i])
+	}
+	return buf
+}
+
+func escapeKey(key []byte) []byte {
+	n := 0
+	for _, c := range key {
+		if c <= escapeByte {
+			n++
+		}
+	}
+	if n == 0 {
+		return key
+	}
+
+	buf := make([]byte, 0, len(key)+n)
+	for _, c := range key {
+		if c <= escapeByte {
+			buf = append(buf, escapeByte, c+1)
+			continue
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
@@ -0,0 +1,51 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestBinary(t *testing.T) {
+	cd := New(&Options{Reduced: true, Binary: true})
+	keys := [][]byte{{0}, {0, 0}, {1}, {0, 1, 2}, {2, 0}, {'a', 0, 'b'}, {'a'}}
+	for i, key := range keys {
+		tt.Nil(t, cd.Insert(key, i))
+	}
+	for i, key := range keys {
+		val, err := cd.Get(key)
+		tt.Nil(t, err)
+		tt.Equal(t, i, val)
+	}
+	tt.Equal(t, len(keys), cd.Len())
+
+	_, err := cd.Get([]byte{0, 0, 0})
+	tt.NotNil(t, err)
+	tt.Equal(t, 3, len(cd.PrefixPredict([]byte{0})))
+	tt.Equal(t, 2, len(cd.PrefixMatch([]byte{0, 0, 9})))
+
+	tt.Nil(t, cd.Delete([]byte{0}))
+	_, err = cd.Get([]byte{0})
+	tt.NotNil(t, err)
+	val, err := cd.Get([]byte{0, 0})
+	tt.Nil(t, err)
+	tt.Equal(t, 1, val)
+
+	b, err := Build(keys, nil, &Options{Reduced: true, Binary: true, Counts: true})
+	tt.Nil(t, err)
+	for i, key := range keys {
+		rank, err := b.Rank(key)
+		tt.Nil(t, err)
+		got, err := b.Select(rank)
+		tt.Nil(t, err)
+		tt.Equal(t, key, got)
+
+		val, err := b.Get(key)
+		tt.Nil(t, err)
+		tt.Equal(t, i, val)
+	}
+	// the escaping keeps the order of the keys.
+	first, err := b.Select(0)
+	tt.Nil(t, err)
+	tt.Equal(t, []byte{0}, first)
+}
@@ -0,0 +1,337 @@
+package gocedar
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// minShardKeys is the fewest keys worth a shard of their own, a smaller set
+// is built in one piece rather than spread over half empty blocks.
+const minShardKeys = 4096
+
+// keyRange is a shard: the sorted keys order[lo:hi], sharing their first
+// `depth` bytes, which are built into a trie of their own below that prefix.
+type keyRange struct {
+	lo, hi, depth int
+}
+
+// Build constructs a trie holding `keys` at once. The sorted keys are cut into
+// ranges of about the same number of keys below a common prefix, every range
+// is built concurrently into its own buffer, and the buffers are packed densely
+// behind the nodes of the prefixes, so a bulk load uses all cores.
+//
+// `vals` may be nil, in which case the value of a key is its index in `keys`.
+// Duplicated keys keep the last value, the same as calling `Insert` in order.
+// The keys holding the byte 0 are rejected, since the label 0 is the one of
+// the terminal nodes, unless Binary escapes the 0. The keys changed by the
+// Normalizer are not kept for Key.
+func Build(keys [][]byte, vals []int, opt *Options) (*Cedar, error) {
+	if vals != nil && len(vals) != len(keys) {
+		return nil, ErrInvalidVal
+	}
+	if opt.Binary || opt.Normalizer != nil {
+		stored := make([][]byte, len(keys))
+		for i, key := range keys {
+			if opt.Normalizer != nil {
+				key = opt.Normalizer(key)
+			}
+			if opt.Binary {
+				key = escapeKey(key)
+			}
+			stored[i] = key
+		}
+		keys = stored
+	}
+
+	order := make([]int, len(keys))
+	for i, key := range keys {
+		if bytes.IndexByte(key, 0) >= 0 {
+			return nil, ErrInvalidKey
+		}
+		if vals != nil && vals[i] == ValLimit {
+			return nil, ErrInvalidVal
+		}
+		order[i] = i
+	}
+	// the duplicated keys stay in order, the last one wins.
+	sort.Slice(order, func(i, j int) bool {
+		if c := bytes.Compare(keys[order[i]], keys[order[j]]); c != 0 {
+			return c < 0
+		}
+		return order[i] < order[j]
+	})
+
+	cd := New(opt)
+	if cd.LoadSize > 0 {
+		cd.Close()
+		return nil, ErrNotEmpty
+	}
+
+	value := func(i int) int {
+		if vals != nil {
+			return vals[i]
+		}
+		return i
+	}
+
+	size := len(keys) / (4 * runtime.GOMAXPROCS(0))
+	if size < minShardKeys {
+		size = minShardKeys
+	}
+	// the empty keys sort first, they end at the root and are inserted with
+	// the keys of the prefixes.
+	empty := 0
+	for empty < len(order) && len(keys[order[empty]]) == 0 {
+		empty++
+	}
+	ranges, top := shardKeys(keys, order[empty:], size)
+	top = append(order[:empty:empty], top...)
+	order = order[empty:]
+
+	tries := make([]*Cedar, len(ranges))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for n, r := range ranges {
+		wg.Add(1)
+		go func(n int, r keyRange) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			shard := New(&Options{Reduced: cd.Reduced, Tail: cd.tail != nil, MaxTrial: cd.maxTrial})
+			for _, i := range order[r.lo:r.hi] {
+				shard.insert(keys[i][r.depth:], value(i))
+			}
+			tries[n] = shard
+		}(n, r)
+	}
+	wg.Wait()
+
+	cd.stitch(keys, order, ranges, tries)
+	// the keys ending at the prefixes of the shards are few, they are inserted.
+	for _, i := range top {
+		cd.insert(keys[i], value(i))
+	}
+	if cd.counts != nil {
+		cd.recount()
+	}
+
+	return cd, nil
+}
+
+// shardKeys cuts the sorted keys into ranges of at most `size` keys below a
+// common prefix. The labels following a prefix are bundled into a range up to
+// `size` keys, and a label leading to more keys is split again by the next
+// byte. The keys equal to a prefix which is split are returned apart.
+func shardKeys(keys [][]byte, order []int, size int) (ranges []keyRange, top []int) {
+	key := func(n int) []byte { return keys[order[n]] }
+	emit := func(lo, hi, depth int) {
+		if lo < hi {
+			ranges = append(ranges, keyRange{lo: lo, hi: hi, depth: depth})
+		}
+	}
+
+	var split func(lo, hi, depth int)
+	split = func(lo, hi, depth int) {
+		if hi-lo <= size {
+			emit(lo, hi, depth)
+			return
+		}
+
+		// the keys ending at the prefix sort first.
+		for ; lo < hi && len(key(lo)) == depth; lo++ {
+			top = append(top, order[lo])
+		}
+
+		start := lo
+		for i := lo; i < hi; {
+			c := key(i)[depth]
+			j := i + sort.Search(hi-i, func(n int) bool { return key(i + n)[depth] > c })
+			switch {
+			case j-i > size:
+				emit(start, i, depth)
+				split(i, j, depth+1)
+				start = j
+			case j-start > size:
+				emit(start, i, depth)
+				start = i
+			}
+			i = j
+		}
+		emit(start, hi, depth)
+	}
+	split(0, len(order), 0)
+
+	return
+}
+
+// stitch makes the nodes of the prefixes of the shards, packs the shards
+// behind them, and hangs each shard below its prefix.
+func (cd *Cedar) stitch(keys [][]byte, order []int, ranges []keyRange, shards []*Cedar) {
+	// the nodes of the prefixes and of the labels following them, `follow`
+	// may move a parent, it is read back from the child.
+	for _, r := range ranges {
+		from := 0
+		for _, c := range keys[order[r.lo]][:r.depth] {
+			from = cd.follow(from, c)
+		}
+		for _, i := range order[r.lo:r.hi] {
+			if c := keys[i][r.depth]; cd.child(from, c) < 0 {
+				from = cd.array[cd.follow(from, c)].check
+			}
+		}
+	}
+
+	// the nodes are moved until the last one is made, they are looked up after.
+	tops := make([][256]int, len(ranges))
+	for n, r := range ranges {
+		from := 0
+		for _, c := range keys[order[r.lo]][:r.depth] {
+			from = cd.child(from, c)
+		}
+		for _, i := range order[r.lo:r.hi] {
+			c := keys[i][r.depth]
+			tops[n][c] = cd.child(from, c)
+		}
+	}
+
+	offsets, blocks := cd.pack(shards)
+	size := blocks << 8
+	if size > cd.capacity {
+		cd.grow(size)
+	}
+	for i := cd.size; i < size; i++ {
+		cd.array[i] = Node{baseV: -1, check: -1}
+		cd.nInfos[i] = NInfo{}
+	}
+	cd.size = size
+
+	for _, shard := range shards {
+		cd.keys += shard.keys
+		if cd.tail != nil {
+			cd.copyTails(shard)
+		}
+	}
+
+	// the shards sharing a block use different nodes of it.
+	var wg sync.WaitGroup
+	for n, shard := range shards {
+		wg.Add(1)
+		go func(n int, shard *Cedar) {
+			defer wg.Done()
+			cd.graft(shard, &tops[n], offsets[n])
+		}(n, shard)
+	}
+	wg.Wait()
+
+	cd.rebuildBlocks()
+}
+
+// child returns the child of `from` labeled `label`, -1 if it is not there.
+func (cd *Cedar) child(from int, label byte) int {
+	base := cd.array[from].base(cd.Reduced)
+	if base < 0 {
+		return -1
+	}
+	if to := base ^ int(label); to < cd.size && cd.array[to].check == from {
+		return to
+	}
+	return -1
+}
+
+// pack returns the offset of every shard, the first block at which the nodes
+// it keeps fall on the free slots of the blocks taken before, and the number
+// of blocks taken. An offset is a whole number of blocks, so that the bases
+// of the shard move along with its nodes.
+func (cd *Cedar) pack(shards []*Cedar) ([]int, int) {
+	type mask [4]uint64
+	set := func(m *mask, i int) { m[i>>6&3] |= 1 << (i & 63) }
+	full := func(m mask) bool { return m[0]&m[1]&m[2]&m[3] == ^uint64(0) }
+
+	taken := make([]mask, cd.size>>8)
+	for i := 0; i < cd.size; i++ {
+		if i == 0 || cd.array[i].check >= 0 {
+			set(&taken[i>>8], i)
+		}
+	}
+
+	offsets := make([]int, len(shards))
+	first := 0 // every block before `first` is full
+	for n, shard := range shards {
+		// the root of the shard and its children are replaced by the nodes of
+		// the prefix, they are not kept.
+		masks := make([]mask, shard.size>>8)
+		for i := 1; i < shard.size; i++ {
+			if shard.array[i].check > 0 {
+				set(&masks[i>>8], i)
+			}
+		}
+
+		for ; first < len(taken) && full(taken[first]); first++ {
+		}
+		fits := func(off int) bool {
+			for j := range masks {
+				if off+j >= len(taken) {
+					return true
+				}
+				for w := range masks[j] {
+					if masks[j][w]&taken[off+j][w] != 0 {
+						return false
+					}
+				}
+			}
+			return true
+		}
+		off := first
+		for !fits(off) {
+			off++
+		}
+
+		for j := range masks {
+			if off+j == len(taken) {
+				taken = append(taken, mask{})
+			}
+			for w := range masks[j] {
+				taken[off+j][w] |= masks[j][w]
+			}
+		}
+		offsets[n] = off << 8
+	}
+
+	// the trailing blocks of the shards may be empty.
+	blocks := len(taken)
+	for blocks > cd.size>>8 && taken[blocks-1] == (mask{}) {
+		blocks--
+	}
+	return offsets, blocks
+}
+
+// graft copies the nodes of `shard` to `off`, and moves the children of the
+// shard root to the nodes `tops` by their labels.
+func (cd *Cedar) graft(shard *Cedar, tops *[256]int, off int) {
+	root := shard.array[0].base(shard.Reduced)
+	for i := 1; i < shard.size; i++ {
+		n := shard.array[i]
+		if n.check < 0 {
+			continue
+		}
+
+		n.baseV = shard.rebased(i, off)
+		if n.check == 0 {
+			to := tops[byte(i^root)]
+			cd.array[to].baseV = n.baseV
+			cd.nInfos[to].child = shard.nInfos[i].child
+			continue
+		}
+
+		if from := n.check; shard.array[from].check == 0 {
+			n.check = tops[byte(from^root)]
+		} else {
+			n.check += off
+		}
+		cd.array[i+off] = n
+		cd.nInfos[i+off] = shard.nInfos[i]
+	}
+}
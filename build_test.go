@@ -0,0 +1,99 @@
+package gocedar
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestBuild(t *testing.T) {
+	keys := make([][]byte, 0)
+	for i := 0; i < 5000; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("%x-%d", i*7919, i)))
+	}
+	for _, word := range words {
+		keys = append(keys, []byte(word))
+	}
+	keys = append(keys, []byte{})
+
+	cd, err := Build(keys, nil, &Options{Reduced: true})
+	tt.Nil(t, err)
+	for i, key := range keys {
+		val, err := cd.Get(key)
+		tt.Nil(t, err)
+		tt.Equal(t, i, val)
+	}
+
+	// the stitched trie keeps working as a normal one.
+	tt.Nil(t, cd.Insert([]byte("魔术"), 1))
+	tt.Nil(t, cd.Delete([]byte(words[0])))
+	_, err = cd.Get([]byte(words[0]))
+	tt.NotNil(t, err)
+	val, err := cd.Get([]byte("魔术"))
+	tt.Nil(t, err)
+	tt.Equal(t, 1, val)
+
+	_, err = Build([][]byte{[]byte("a"), []byte("a\x00b")}, nil, &Options{Reduced: true})
+	tt.Equal(t, ErrInvalidKey, err)
+
+	// the empty key is taken in every mode, the last one wins.
+	for _, o := range []Options{{}, {Reduced: true}, {Tail: true}} {
+		cd, err = Build([][]byte{{}, []byte("a"), {}}, []int{1, 2, 3}, &o)
+		tt.Nil(t, err)
+		val, err = cd.Get(nil)
+		tt.Nil(t, err)
+		tt.Equal(t, 3, val)
+		tt.Equal(t, 2, cd.Len())
+		tt.Nil(t, cd.Verify())
+	}
+}
+
+func TestBuildShards(t *testing.T) {
+	// the keys share a long prefix, and their leading bytes are few.
+	keys := make([][]byte, 0)
+	for i := 0; i < 20000; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("歌曲%d-%x", i%7, i*7919)))
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return bytes.Compare(keys[order[i]], keys[order[j]]) < 0 })
+
+	ranges, top := shardKeys(keys, order, 1000)
+	tt.Equal(t, 0, len(top))
+	tt.True(t, len(ranges) >= 20)
+	for _, r := range ranges {
+		tt.True(t, r.hi-r.lo <= 1000)
+	}
+
+	cd, err := Build(keys, nil, &Options{Reduced: true})
+	tt.Nil(t, err)
+	tt.Nil(t, cd.Verify())
+	tt.Equal(t, len(keys), cd.Len())
+	for i, key := range keys {
+		val, err := cd.Get(key)
+		tt.Nil(t, err)
+		tt.Equal(t, i, val)
+	}
+
+	// the shards are packed as densely as the keys inserted one by one.
+	one := New(&Options{Reduced: true})
+	for i, key := range keys {
+		tt.Nil(t, one.Insert(key, i))
+	}
+	tt.True(t, cd.capacity <= one.capacity)
+
+	// a small set scattered over many leading bytes takes no block per byte.
+	keys = keys[:0]
+	for i := 0; i < 200; i++ {
+		keys = append(keys, []byte{byte('!' + i%90), byte('a' + i%26), byte('0' + i%10)})
+	}
+	cd, err = Build(keys, nil, &Options{Tail: true})
+	tt.Nil(t, err)
+	tt.Nil(t, cd.Verify())
+	tt.True(t, cd.capacity <= 1024)
+}
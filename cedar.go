@@ -55,6 +55,25 @@ func (b *Block) init() {
 type Cedar struct {
 	mmap *MMap
 	*MetaInfo
+	*metaExt
+
+	// tail stores the single-branch suffixes of the leaves, nil if not enabled.
+	tail *tailPool
+	// counts holds the number of keys below every node, nil if not enabled.
+	counts []int
+	// payload stores the blobs attached to the keys, nil until the first one.
+	payload *payloadStore
+	// postings stores the ids added to the keys, nil until the first one.
+	postings *payloadStore
+	// origins stores the keys changed by the Normalizer, nil until the first one.
+	origins   *payloadStore
+	normalize Normalizer
+	// gen counts the nodes taken and released, a Cursor made before a change
+	// of it may point to a moved node.
+	gen uint64
+	// resolves and relocated count the conflicts met by `resolve` and the
+	// nodes it moved, since the trie was made or loaded.
+	resolves, relocated int
 
 	// Reduced option the reduced trie
 	// Reduced bool
@@ -75,9 +94,12 @@ type Cedar struct {
 }
 
 const (
-	// ValLimit cedar value limit
+	// ValLimit cedar value limit, the one value reserved for the keys without value
 	ValLimit = int(^uint(0) >> 1)
 	// NoVal not have value
+	//
+	// Deprecated: -1 is a value like the others, a key without value is the
+	// one holding ValLimit.
 	NoVal = -1
 )
 
@@ -85,9 +107,37 @@ const (
 // }
 
 type Options struct {
+	// Reduced keeps the value of a key without a longer one in its last node
+	// instead of a terminal node. A mmap trie must be loaded in the encoding it
+	// was built with, see Convert.
 	Reduced  bool
 	UseMMap  bool
 	MMapPath string
+	// Tail stores the suffixes only used by one key in a tail pool instead of
+	// one node per byte, it implies Reduced. A loaded mmap trie keeps the mode
+	// it was built with.
+	Tail bool
+	// Counts keeps the number of keys below every node for Rank and Select,
+	// in either encoding. The counts are not persisted, and are rebuilt when
+	// a mmap trie is loaded.
+	Counts bool
+	// Binary escapes the keys so that they may hold any byte, including the 0
+	// used by the terminal nodes. A loaded mmap trie keeps the mode it was built with.
+	Binary bool
+	// Normalizer maps the keys given to Insert, Get, Delete and the prefix
+	// queries to the keys stored, see Chain and the Fold normalizers. The keys
+	// it changes are kept for Key. It is not persisted, a loaded mmap trie
+	// must be given the same one.
+	Normalizer Normalizer
+	// Unordered keeps the children of a node in the order they were added
+	// rather than by their labels, which inserts faster. The iterations and
+	// PrefixPredict sort the children on the fly. A loaded mmap trie keeps the
+	// mode it was built with.
+	Unordered bool
+	// MaxTrial is the number of times the search of free nodes probes a block
+	// before giving it up, 1 if not set. A larger one packs the nodes denser
+	// and inserts slower. A loaded mmap trie keeps the one it was built with.
+	MaxTrial int
 }
 
 // New initialize the Cedar for further use
@@ -100,20 +150,54 @@ func New(opt *Options) *Cedar {
 		mmap := NewMMap(opt.MMapPath)
 		mmap.InitData(cd)
 		cd.useMMap = true
+		if hasRegion(opt.MMapPath, tailFileName) || (cd.LoadSize == 0 && opt.Tail) {
+			cd.tail = newTailPool(openRegion(opt.MMapPath, tailFileName))
+		}
+		if hasRegion(opt.MMapPath, payloadFileName) {
+			cd.payloads()
+		}
+		if hasRegion(opt.MMapPath, postingFileName) {
+			cd.postingLists()
+		}
+		if hasRegion(opt.MMapPath, originFileName) {
+			cd.originKeys()
+		}
 	} else {
 		cd.MetaInfo = &MetaInfo{}
+		cd.metaExt = &metaExt{}
 		cd.array = make([]Node, 256)
 		cd.nInfos = make([]NInfo, 256)
 		cd.blocks = make([]Block, 1)
+		if opt.Tail {
+			cd.tail = newTailPool(&region{})
+		}
 	}
+	if opt.Counts {
+		cd.counts = make([]int, len(cd.array))
+	}
+	cd.normalize = opt.Normalizer
 	if cd.LoadSize > 0 { // if there is data in mmap, do not need init meta
+		reduced := isReduced(opt.Reduced) || opt.Tail
+		_assert(cd.Reduced == reduced,
+			"the trie in %s is reduced %v, not %v, convert it with Convert", opt.MMapPath, cd.Reduced, reduced)
+		if cd.version == 0 { // written before the file `meta`
+			cd.migrate()
+		}
+		if cd.counts != nil {
+			cd.recount()
+		}
 		return cd
 	}
-	cd.Reduced = isReduced(opt.Reduced)
+	cd.version = metaVersion
+	cd.Reduced = isReduced(opt.Reduced) || cd.tail != nil
+	cd.binary = opt.Binary
 	cd.capacity = 256
 	cd.size = 256
-	cd.ordered = true
+	cd.ordered = !opt.Unordered
 	cd.maxTrial = 1
+	if opt.MaxTrial > 0 {
+		cd.maxTrial = opt.MaxTrial
+	}
 
 	if !cd.Reduced {
 		cd.array[0] = Node{baseV: 0, check: -1}
@@ -142,6 +226,10 @@ func New(opt *Options) *Cedar {
 // it is used by the `update` to populate the trie.
 func (cd *Cedar) follow(from int, label byte) (to int) {
 	base := cd.array[from].base(cd.Reduced)
+	// the terminal node of the root would be the root itself under the base 0.
+	if from == 0 && label == 0 && base == 0 {
+		return cd.moveRoot()
+	}
 
 	// the node is not there
 	to = base ^ int(label)
@@ -169,6 +257,7 @@ func (cd *Cedar) follow(from int, label byte) (to int) {
 // pop empty node from block; never transfer the special block (idx = 0)
 // nolint
 func (cd *Cedar) popENode(base, from int, label byte) int {
+	cd.gen++
 	e := base ^ int(label)
 	if base < 0 {
 		e = cd.findPlace()
@@ -205,7 +294,7 @@ func (cd *Cedar) popENode(base, from int, label byte) int {
 		if label != 0 {
 			cd.array[e].baseV = -1
 		} else {
-			cd.array[e].baseV = 0
+			cd.array[e].baseV = ValLimit
 		}
 		cd.array[e].check = from
 		if base < 0 {
@@ -228,6 +317,7 @@ func (cd *Cedar) popENode(base, from int, label byte) int {
 // push empty node into empty ring
 // nolint
 func (cd *Cedar) pushENode(e int) {
+	cd.gen++
 	idx := e >> 8
 	b := &cd.blocks[idx]
 	b.num++
@@ -267,6 +357,12 @@ func (cd *Cedar) pushENode(e int) {
 	}
 	// reset ninfo; no child, no sibling
 	cd.nInfos[e] = NInfo{}
+	if cd.counts != nil {
+		cd.counts[e] = 0
+	}
+	cd.eachStore(func(p *payloadStore) {
+		p.setRef(e, 0)
+	})
 }
 
 // push the `label` into the sibling chain
@@ -466,6 +562,8 @@ func (cd *Cedar) resolve(fromN, baseN int, labelN byte) int {
 		base = cd.findPlaces(children)
 	}
 	base ^= int(children[0])
+	cd.resolves++
+	cd.relocated += len(children)
 
 	var from, nbase int
 	if flag {
@@ -497,6 +595,32 @@ func (cd *Cedar) resolve(fromN, baseN int, labelN byte) int {
 	return toPn
 }
 
+// moveRoot moves the children of the root away from the base 0 to make room
+// for the terminal node of the empty key, and returns that node.
+func (cd *Cedar) moveRoot() int {
+	// the children of the base 0 are the nodes of their labels, the chain
+	// starts at the root itself as the node of the label 0.
+	children := []byte{0}
+	for c := cd.nInfos[0].sibling; c != 0; c = cd.nInfos[c].sibling {
+		children = append(children, c)
+	}
+
+	base := 0
+	if len(children) == 1 {
+		base = cd.findPlace()
+	} else {
+		base = cd.findPlaces(children)
+	}
+	cd.nInfos[0] = NInfo{}
+	if !cd.Reduced {
+		cd.array[0].baseV = base
+	} else {
+		cd.array[0].baseV = -base - 1
+	}
+	cd.listN(base, 0, 0, 0, 0, 0, children, true)
+	return base
+}
+
 func (cd *Cedar) listN(base, from, nbase, fromN, toPn int,
 	labelN byte, children []byte, flag bool) (int, byte, int) {
 	// the actual work for relocating the chilren
@@ -518,6 +642,12 @@ func (cd *Cedar) listN(base, from, nbase, fromN, toPn int,
 		arr := &cd.array[to]
 		arrs := &cd.array[newTo]
 		arr.baseV = arrs.baseV
+		if cd.counts != nil {
+			cd.counts[to] = cd.counts[newTo]
+		}
+		cd.eachStore(func(p *payloadStore) {
+			p.setRef(to, p.ref(newTo))
+		})
 
 		condition := false
 		if !cd.Reduced {
@@ -552,12 +682,18 @@ func (cd *Cedar) listN(base, from, nbase, fromN, toPn int,
 		// clean up the space that was moved away from.
 		cd.pushSibling(fromN, toPn^int(labelN), labelN, true)
 		cd.nInfos[newTo].child = 0
+		if cd.counts != nil {
+			cd.counts[newTo] = 0
+		}
+		cd.eachStore(func(p *payloadStore) {
+			p.setRef(newTo, 0)
+		})
 
 		if !cd.Reduced {
 			if labelN != 0 {
 				arrs.baseV = -1
 			} else {
-				arrs.baseV = 0
+				arrs.baseV = ValLimit
 			}
 		} else {
 			arrs.baseV = ValLimit
@@ -604,26 +740,10 @@ func (cd *Cedar) pushBlock(idx int, to *int, empty bool) {
 func (cd *Cedar) addBlock() int {
 	if cd.size == cd.capacity {
 		if cd.capacity*int(unsafe.Sizeof(Node{})) > maxMemStep {
-			cd.capacity += maxMemStep / int(unsafe.Sizeof(Node{}))
+			cd.grow(cd.capacity + maxMemStep/int(unsafe.Sizeof(Node{})))
 		} else {
-			cd.capacity += cd.capacity
+			cd.grow(cd.capacity + cd.capacity)
 		}
-		if cd.useMMap {
-			cd.mmap.AddBlock(cd, cd.capacity)
-		} else {
-			array := cd.array
-			cd.array = make([]Node, cd.capacity)
-			copy(cd.array, array)
-
-			nInfos := cd.nInfos
-			cd.nInfos = make([]NInfo, cd.capacity)
-			copy(cd.nInfos, nInfos)
-
-			blocks := cd.blocks
-			cd.blocks = make([]Block, cd.capacity>>8)
-			copy(cd.blocks, blocks)
-		}
-
 	}
 
 	cd.blocks[cd.size>>8].init()
@@ -642,6 +762,114 @@ func (cd *Cedar) addBlock() int {
 	return cd.size>>8 - 1
 }
 
+// grow reallocates the `array`, `nInfos` and `blocks` so that they can hold `capacity` nodes.
+func (cd *Cedar) grow(capacity int) {
+	cd.capacity = capacity
+	cd.fit()
+	if cd.useMMap {
+		cd.mmap.AddBlock(cd, cd.capacity)
+		return
+	}
+
+	array := cd.array
+	cd.array = make([]Node, cd.capacity)
+	copy(cd.array, array)
+
+	nInfos := cd.nInfos
+	cd.nInfos = make([]NInfo, cd.capacity)
+	copy(cd.nInfos, nInfos)
+
+	blocks := cd.blocks
+	cd.blocks = make([]Block, cd.capacity>>8)
+	copy(cd.blocks, blocks)
+}
+
+// fit resizes the counts and the offsets of the stores, kept for every node,
+// to the capacity.
+func (cd *Cedar) fit() {
+	if cd.counts != nil {
+		counts := cd.counts
+		cd.counts = make([]int, cd.capacity)
+		copy(cd.counts, counts)
+	}
+	cd.eachStore(func(p *payloadStore) {
+		p.fit(cd.capacity)
+	})
+}
+
+// rebuildBlocks recomputes the empty rings and the Full/Closed/Open block lists
+// from the `check` of every node, it is used when the array was laid out directly
+// instead of through `popENode` and `pushENode`.
+func (cd *Cedar) rebuildBlocks() {
+	cd.blocksHeadFull, cd.blocksHeadClosed, cd.blocksHeadOpen = 0, 0, 0
+	for i := 0; i <= 256; i++ {
+		cd.reject[i] = i + 1
+	}
+
+	free := make([]int, 0, 256)
+	for idx := 0; idx < cd.size>>8; idx++ {
+		free = free[:0]
+		for e := idx << 8; e < (idx+1)<<8; e++ {
+			if e != 0 && cd.array[e].check < 0 {
+				free = append(free, e)
+			}
+		}
+
+		b := &cd.blocks[idx]
+		*b = Block{}
+		b.init()
+		b.num = len(free)
+
+		// link the free nodes as a cyclic doubly-linked list
+		for i, e := range free {
+			prev := free[(i+len(free)-1)%len(free)]
+			next := free[(i+1)%len(free)]
+			cd.array[e] = Node{baseV: -prev, check: -next}
+			cd.nInfos[e] = NInfo{}
+		}
+		if len(free) > 0 {
+			b.eHead = free[0]
+		}
+
+		// the root is never handed out, so it is counted as a free slot of the
+		// special block, the same as `New` does.
+		if idx == 0 {
+			b.num++
+			continue
+		}
+
+		switch b.num {
+		case 0:
+			cd.pushBlock(idx, &cd.blocksHeadFull, cd.blocksHeadFull == 0)
+		case 1:
+			cd.pushBlock(idx, &cd.blocksHeadClosed, cd.blocksHeadClosed == 0)
+		default:
+			cd.pushBlock(idx, &cd.blocksHeadOpen, cd.blocksHeadOpen == 0)
+		}
+	}
+}
+
+// rebased returns the `baseV` of the node at `i` as if the whole array was moved by `off`,
+// values stored in the leaves are left untouched.
+func (cd *Cedar) rebased(i, off int) int {
+	n := cd.array[i]
+	if cd.isTerminal(i) {
+		return n.baseV
+	}
+	if cd.Reduced {
+		if n.baseV < 0 {
+			return n.baseV - off
+		}
+		return n.baseV
+	}
+
+	// childless node.
+	if n.baseV < 0 {
+		return n.baseV
+	}
+	return n.baseV + off
+}
+
 // transfer the block at idx from the linked-list of `from` to the linked-list of `to`,
 // specially handle the case where the destination linked-list is empty.
 func (cd *Cedar) transferBlock(idx int, from, to *int) {
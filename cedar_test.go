@@ -88,3 +88,125 @@ func TestFFind(t *testing.T) {
 	// tt.Nil(t, err)
 	// // tt.Equal(t, 3, val)
 }
+
+func TestNegative(t *testing.T) {
+	for _, reduced := range []bool{true, false} {
+		cd := New(&Options{Reduced: reduced})
+		tt.Nil(t, cd.Insert([]byte("score"), -5))
+		tt.Nil(t, cd.Insert([]byte("scores"), 3))
+		tt.Nil(t, cd.Insert([]byte("min"), -ValLimit))
+		tt.Equal(t, ErrInvalidVal, cd.Insert([]byte("max"), ValLimit))
+
+		tt.Nil(t, cd.Update([]byte("scores"), -10))
+		tt.Nil(t, cd.Update([]byte("delta"), -1))
+		tt.Nil(t, cd.Update([]byte("delta"), -1))
+
+		val, err := cd.Get([]byte("score"))
+		tt.Nil(t, err)
+		tt.Equal(t, -5, val)
+		val, err = cd.Get([]byte("scores"))
+		tt.Nil(t, err)
+		tt.Equal(t, -7, val)
+		val, err = cd.Get([]byte("delta"))
+		tt.Nil(t, err)
+		tt.Equal(t, -2, val)
+		val, err = cd.Find([]byte("min"), 0)
+		tt.Nil(t, err)
+		tt.Equal(t, -ValLimit, val)
+
+		// the sums wrapping around the range are rejected.
+		tt.Equal(t, ErrInvalidVal, cd.Update([]byte("min"), -2))
+		tt.Nil(t, cd.Update([]byte("top"), ValLimit-1))
+		tt.Equal(t, ErrInvalidVal, cd.Update([]byte("top"), 1))
+		tt.Equal(t, ErrInvalidVal, cd.Update([]byte("top"), ValLimit-1))
+		val, err = cd.Get([]byte("min"))
+		tt.Nil(t, err)
+		tt.Equal(t, -ValLimit, val)
+		tt.Nil(t, cd.Delete([]byte("top")))
+
+		// the prefix and the extension of a key are deleted on their own.
+		tt.Nil(t, cd.Delete([]byte("score")))
+		_, err = cd.Get([]byte("score"))
+		tt.NotNil(t, err)
+		val, err = cd.Get([]byte("scores"))
+		tt.Nil(t, err)
+		tt.Equal(t, -7, val)
+		tt.Nil(t, cd.Delete([]byte("scores")))
+		tt.NotNil(t, cd.Delete([]byte("scores")))
+		tt.Nil(t, cd.Delete([]byte("min")))
+		tt.Nil(t, cd.Delete([]byte("delta")))
+		tt.Equal(t, 0, cd.Len())
+		tt.Nil(t, cd.Verify())
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	dir := t.TempDir()
+	cd := New(&Options{Reduced: true, Unordered: true, MaxTrial: 4, UseMMap: true, MMapPath: dir})
+	keys := []string{"zoo", "apple", "mango", "app", "banana", "zip", "a"}
+	for i, key := range keys {
+		tt.Nil(t, cd.Insert([]byte(key), i))
+	}
+	tt.Nil(t, cd.Verify())
+	cd.Close()
+
+	// the mode is kept in the mmap files.
+	cd = New(&Options{Reduced: true, UseMMap: true, MMapPath: dir})
+	defer cd.Close()
+	tt.False(t, cd.ordered)
+	tt.Equal(t, 4, cd.maxTrial)
+
+	var got []string
+	_ = cd.walk(0, nil, func(key []byte, _ int) error {
+		got = append(got, string(key))
+		return nil
+	})
+	tt.Equal(t, []string{"a", "app", "apple", "banana", "mango", "zip", "zoo"}, got)
+
+	// PrefixPredict follows the order of Walk.
+	got = got[:0]
+	for _, id := range cd.PrefixPredict([]byte("")) {
+		val, err := cd.Value(id)
+		tt.Nil(t, err)
+		got = append(got, keys[val])
+	}
+	tt.Equal(t, []string{"a", "app", "apple", "banana", "mango", "zip", "zoo"}, got)
+	ids := cd.PrefixPredict([]byte("z"), 1)
+	tt.Equal(t, 1, len(ids))
+	val, err := cd.Value(ids[0])
+	tt.Nil(t, err)
+	tt.Equal(t, "zip", keys[val])
+
+	_, err = cd.Compact()
+	tt.Nil(t, err)
+	tt.False(t, cd.ordered)
+	tt.Equal(t, 4, cd.maxTrial)
+	val, err = cd.Get([]byte("banana"))
+	tt.Nil(t, err)
+	tt.Equal(t, 4, val)
+}
+
+func TestEmptyKey(t *testing.T) {
+	for _, o := range []Options{{}, {Reduced: true}, {Tail: true}, {Unordered: true}} {
+		cd := New(&o)
+		tt.Nil(t, cd.Insert([]byte("a"), 1))
+		tt.Nil(t, cd.Insert([]byte("b"), 2))
+		// the children of the root are moved for its terminal node.
+		tt.Nil(t, cd.Insert([]byte(""), -3))
+		tt.Nil(t, cd.Insert([]byte("c"), 4))
+		tt.Nil(t, cd.Verify())
+		tt.Equal(t, 4, cd.Len())
+
+		val, err := cd.Get(nil)
+		tt.Nil(t, err)
+		tt.Equal(t, -3, val)
+		val, err = cd.Get([]byte("b"))
+		tt.Nil(t, err)
+		tt.Equal(t, 2, val)
+
+		tt.Nil(t, cd.Delete(nil))
+		_, err = cd.Get(nil)
+		tt.NotNil(t, err)
+		tt.Nil(t, cd.Verify())
+	}
+}
@@ -0,0 +1,82 @@
+package gocedar
+
+import "unicode/utf8"
+
+// Edge is an outgoing edge of a node.
+type Edge struct {
+	Label byte
+	To    int
+}
+
+// RuneEdge is an outgoing path of a node spelling one UTF-8 character.
+type RuneEdge struct {
+	Rune rune
+	To   int
+}
+
+// Children returns the edges going out of the node `id` in the order of the
+// labels. The terminal node holding the value of the key ending at `id` is not
+// an edge, see Value. With Tail the bytes in the tail of a leaf are not nodes,
+// and with Binary the labels are the escaped bytes.
+func (cd *Cedar) Children(id int) []Edge {
+	var edges []Edge
+	cd.children(id, func(label byte, to int) bool {
+		if label != 0 {
+			edges = append(edges, Edge{Label: label, To: to})
+		}
+		return true
+	})
+	return edges
+}
+
+// RuneChildren returns the characters going out of the node `id`, grouping the
+// bytes of a character into one edge to the node after its last byte. With
+// Tail a character finished in the tail of a leaf is an edge to the leaf. The
+// paths ending inside a character, or not valid UTF-8, are left out.
+func (cd *Cedar) RuneChildren(id int) []RuneEdge {
+	var edges []RuneEdge
+	buf := make([]byte, 0, utf8.UTFMax)
+
+	var visit func(from int)
+	visit = func(from int) {
+		if v := cd.array[from].baseV; cd.tail != nil && len(buf) > 0 && v >= 0 && v != ValLimit {
+			suffix, _ := cd.tail.entry(v)
+			if need := runeLen(buf[0]) - len(buf); need <= len(suffix) {
+				b := append(buf[:len(buf):len(buf)], suffix[:need]...)
+				if r, size := utf8.DecodeRune(b); r != utf8.RuneError && size == len(b) {
+					edges = append(edges, RuneEdge{Rune: r, To: from})
+				}
+			}
+			return
+		}
+
+		for _, e := range cd.Children(from) {
+			buf = append(buf, e.Label)
+			if n := runeLen(buf[0]); len(buf) < n {
+				visit(e.To)
+			} else if r, size := utf8.DecodeRune(buf); r != utf8.RuneError && size == len(buf) {
+				edges = append(edges, RuneEdge{Rune: r, To: e.To})
+			}
+			buf = buf[:len(buf)-1]
+		}
+	}
+	visit(id)
+
+	return edges
+}
+
+// runeLen returns the number of bytes of the character starting with `b`, by
+// its leading bits, 1 for the bytes which can not start one.
+func runeLen(b byte) int {
+	switch {
+	case b >= 0xF8:
+		return 1
+	case b >= 0xF0:
+		return 4
+	case b >= 0xE0:
+		return 3
+	case b >= 0xC0:
+		return 2
+	}
+	return 1
+}
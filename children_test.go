@@ -0,0 +1,58 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestChildren(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	for i, key := range []string{"ab", "ac", "a", "梦", "梦想", "夜"} {
+		tt.Nil(t, cd.Insert([]byte(key), i))
+	}
+
+	edges := cd.Children(0)
+	tt.Equal(t, 3, len(edges))
+	tt.Equal(t, byte('a'), edges[0].Label)
+
+	a := edges[0].To
+	edges = cd.Children(a)
+	tt.Equal(t, 2, len(edges))
+	tt.Equal(t, byte('b'), edges[0].Label)
+	tt.Equal(t, byte('c'), edges[1].Label)
+	val, err := cd.Value(edges[1].To)
+	tt.Nil(t, err)
+	tt.Equal(t, 1, val)
+
+	runes := cd.RuneChildren(0)
+	tt.Equal(t, 3, len(runes))
+	tt.Equal(t, 'a', runes[0].Rune)
+	var dream int
+	for _, e := range runes {
+		if e.Rune == '梦' {
+			dream = e.To
+		}
+	}
+	val, err = cd.Value(dream)
+	tt.Nil(t, err)
+	tt.Equal(t, 3, val)
+
+	runes = cd.RuneChildren(dream)
+	tt.Equal(t, 1, len(runes))
+	tt.Equal(t, '想', runes[0].Rune)
+
+	// the characters finished in the tails lead to the leaves.
+	cd = New(&Options{Tail: true})
+	for i, key := range []string{"周", "杰", "伦", "伦敦"} {
+		tt.Nil(t, cd.Insert([]byte(key), i))
+	}
+	runes = cd.RuneChildren(0)
+	tt.Equal(t, 3, len(runes))
+	for i, r := range []rune("伦周杰") {
+		tt.Equal(t, r, runes[i].Rune)
+	}
+	val, err = cd.Value(runes[1].To)
+	tt.Nil(t, err)
+	tt.Equal(t, 0, val)
+}
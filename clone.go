@@ -0,0 +1,61 @@
+package gocedar
+
+// Clone returns a copy of the trie, node for node, on the heap or in the mmap
+// files of `opt.MMapPath`, which must be empty. Only UseMMap and MMapPath of
+// `opt` are used, the copy keeps the modes and the Normalizer of the trie,
+// with its tail pool, counts, payloads, posting lists and the keys kept for
+// Key. `opt` may be nil for a copy on the heap.
+func (cd *Cedar) Clone(opt *Options) (*Cedar, error) {
+	o := Options{
+		Reduced:    cd.Reduced,
+		Tail:       cd.tail != nil,
+		Counts:     cd.counts != nil,
+		Binary:     cd.binary,
+		Normalizer: cd.normalize,
+		Unordered:  !cd.ordered,
+		MaxTrial:   cd.maxTrial,
+	}
+	if opt != nil {
+		o.UseMMap, o.MMapPath = opt.UseMMap, opt.MMapPath
+	}
+
+	out := New(&o)
+	if out.LoadSize > 0 {
+		out.Close()
+		return nil, ErrNotEmpty
+	}
+
+	open := [...]func() *payloadStore{out.payloads, out.postingLists, out.originKeys}
+	for i, p := range cd.stores() {
+		if p != nil {
+			open[i]()
+		}
+	}
+
+	out.grow(cd.capacity)
+	copy(out.array, cd.array[:cd.capacity])
+	copy(out.nInfos, cd.nInfos[:cd.capacity])
+	copy(out.blocks, cd.blocks[:cd.capacity>>8])
+	copy(out.counts, cd.counts)
+
+	meta := *cd.MetaInfo
+	meta.useMMap, meta.LoadSize = out.useMMap, out.LoadSize
+	*out.MetaInfo = meta
+	out.keys = cd.keys
+
+	if cd.tail != nil {
+		out.tail.resize(len(cd.tail.data))
+		copy(out.tail.data, cd.tail.data)
+	}
+	for i, p := range cd.stores() {
+		if p == nil {
+			continue
+		}
+		q := out.stores()[i]
+		q.blobs.resize(len(p.blobs.data))
+		copy(q.blobs.data, p.blobs.data)
+		copy(q.refs.data, p.refs.data)
+	}
+
+	return out, nil
+}
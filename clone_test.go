@@ -0,0 +1,50 @@
+package gocedar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestClone(t *testing.T) {
+	cd := New(&Options{Tail: true, Counts: true})
+	for i := 0; i < 1000; i++ {
+		tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("clone-%d", i)), i))
+	}
+	tt.Nil(t, cd.PutPayload([]byte("clone-1"), []byte("one")))
+
+	// heap to mmap, the copy is persisted.
+	dir := t.TempDir()
+	mc, err := cd.Clone(&Options{UseMMap: true, MMapPath: dir})
+	tt.Nil(t, err)
+	tt.Nil(t, mc.Verify())
+	tt.Nil(t, mc.Insert([]byte("clone-new"), -1))
+	mc.Close()
+
+	_, err = cd.Clone(&Options{UseMMap: true, MMapPath: dir})
+	tt.Equal(t, ErrNotEmpty, err)
+
+	mc = New(&Options{Tail: true, Counts: true, UseMMap: true, MMapPath: dir})
+	defer mc.Close()
+	tt.Equal(t, 1001, mc.Len())
+	data, err := mc.GetPayload([]byte("clone-1"))
+	tt.Nil(t, err)
+	tt.Equal(t, "one", string(data))
+
+	// mmap to heap, the changes do not touch the files.
+	hc, err := mc.Clone(nil)
+	tt.Nil(t, err)
+	tt.False(t, hc.useMMap)
+	tt.Nil(t, hc.Delete([]byte("clone-new")))
+	tt.Nil(t, hc.Verify())
+	rank, err := hc.Rank([]byte("clone-10"))
+	tt.Nil(t, err)
+	tt.Equal(t, 2, rank)
+
+	val, err := mc.Get([]byte("clone-new"))
+	tt.Nil(t, err)
+	tt.Equal(t, -1, val)
+	_, err = cd.Get([]byte("clone-new"))
+	tt.NotNil(t, err)
+}
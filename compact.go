@@ -0,0 +1,124 @@
+package gocedar
+
+// Compact rebuilds the trie into a tight layout, dropping the holes left by
+// `Delete`, and shrinks the backing arrays (or the mmap files) to fit. The
+// nodes are kept in place if the rebuilt layout is not smaller, the records
+// of the payloads and the posting lists are packed anyway. It returns the
+// number of bytes reclaimed.
+func (cd *Cedar) Compact() (int, error) {
+	var (
+		keys [][]byte
+		vals []int
+	)
+	err := cd.walk(0, nil, func(key []byte, val int) error {
+		keys = append(keys, append([]byte(nil), key...))
+		vals = append(vals, val)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// the records of the stores, by store and by key.
+	records := make([][][]byte, len(cd.stores()))
+	for s, p := range cd.stores() {
+		if p == nil {
+			continue
+		}
+		records[s] = make([][]byte, len(keys))
+		for i, key := range keys {
+			to, _ := cd.valueNode(key)
+			if off := p.ref(to); off != 0 {
+				records[s][i] = append([]byte(nil), p.get(off)...)
+			}
+		}
+	}
+
+	tmp, err := Build(keys, vals, &Options{
+		Reduced:   cd.Reduced,
+		Tail:      cd.tail != nil,
+		Unordered: !cd.ordered,
+		MaxTrial:  cd.maxTrial,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	before := cd.backingSize()
+	after, now := arraysSize(tmp.capacity), arraysSize(cd.capacity)
+	if cd.tail != nil {
+		after, now = after+tmp.tail.used(), now+len(cd.tail.data)
+	}
+	if after < now {
+		cd.adopt(tmp)
+	}
+	for s, p := range cd.stores() {
+		if p == nil {
+			continue
+		}
+		// the records are written again one after another, dropping the free ones.
+		p.reset(cd.capacity)
+		for i, data := range records[s] {
+			if data != nil {
+				to, _ := cd.valueNode(keys[i])
+				p.setRef(to, p.put(0, data))
+			}
+		}
+		p.blobs.resize(p.used())
+	}
+
+	return before - cd.backingSize(), nil
+}
+
+// adopt takes the nodes, the tail pool and the meta info of `tmp`, a trie of
+// the same mode built on the heap, in place of its own. The records of the
+// stores are left to the caller.
+func (cd *Cedar) adopt(tmp *Cedar) {
+	cd.gen++
+	cd.shrink(tmp.capacity)
+	copy(cd.array, tmp.array)
+	copy(cd.nInfos, tmp.nInfos)
+	copy(cd.blocks, tmp.blocks)
+	if cd.tail != nil {
+		cd.tail.resize(tmp.tail.used())
+		copy(cd.tail.data, tmp.tail.data)
+	}
+
+	meta := *tmp.MetaInfo
+	meta.useMMap, meta.LoadSize = cd.useMMap, cd.LoadSize
+	*cd.MetaInfo = meta
+	cd.keys = tmp.keys
+	if cd.counts != nil {
+		cd.recount()
+	}
+}
+
+// backingSize returns the bytes held by the arrays, the meta info, the tail
+// pool, the payloads and the postings, for mmap it is the total size of the files.
+func (cd *Cedar) backingSize() int {
+	size := metaSize + extSize + arraysSize(cd.capacity)
+	if cd.tail != nil {
+		size += len(cd.tail.data)
+	}
+	cd.eachStore(func(p *payloadStore) {
+		size += len(p.blobs.data) + len(p.refs.data)
+	})
+	return size
+}
+
+// arraysSize returns the bytes of the arrays holding `capacity` nodes.
+func arraysSize(capacity int) int {
+	return capacity*(nodeSize+nInfoSize) + capacity>>8*blockSize
+}
+
+// shrink reallocates the backing arrays to exactly `capacity` nodes, truncating
+// the files for mmap.
+func (cd *Cedar) shrink(capacity int) {
+	if !cd.useMMap {
+		cd.grow(capacity)
+		return
+	}
+
+	cd.capacity = capacity
+	cd.fit()
+	cd.mmap.Truncate(cd, capacity)
+}
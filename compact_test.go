@@ -0,0 +1,104 @@
+package gocedar
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestCompact(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	for i := 0; i < 10000; i++ {
+		tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("song-%d", i)), i))
+	}
+	for i := 0; i < 10000; i += 2 {
+		tt.Nil(t, cd.Delete([]byte(fmt.Sprintf("song-%d", i))))
+	}
+
+	reclaimed, err := cd.Compact()
+	tt.Nil(t, err)
+	tt.True(t, reclaimed > 0)
+
+	for i := 1; i < 10000; i += 2 {
+		val, err := cd.Get([]byte(fmt.Sprintf("song-%d", i)))
+		tt.Nil(t, err)
+		tt.Equal(t, i, val)
+	}
+	_, err = cd.Get([]byte("song-0"))
+	tt.NotNil(t, err)
+
+	tt.Nil(t, cd.Insert([]byte("song-0"), 0))
+	val, err := cd.Get([]byte("song-0"))
+	tt.Nil(t, err)
+	tt.Equal(t, 0, val)
+
+	// the empty key is a key like the others.
+	for _, o := range []Options{{Reduced: true}, {Tail: true}} {
+		cd = New(&o)
+		tt.Nil(t, cd.Insert([]byte(""), -1))
+		for i := 0; i < 1000; i++ {
+			tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("song-%d", i)), i))
+		}
+		_, err = cd.Compact()
+		tt.Nil(t, err)
+		val, err = cd.Get([]byte(""))
+		tt.Nil(t, err)
+		tt.Equal(t, -1, val)
+		tt.Equal(t, 1001, cd.Len())
+		tt.Nil(t, cd.Verify())
+	}
+}
+
+func TestCompactNotSmaller(t *testing.T) {
+	scattered := make([][]byte, 0)
+	for i := 0; i < 200; i++ {
+		scattered = append(scattered, []byte{byte('!' + i%90), byte('a' + i%26), byte('0' + i%10)})
+	}
+	random := make([][]byte, 0)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		random = append(random, []byte(fmt.Sprintf("%x", r.Int63())))
+	}
+
+	for _, keys := range [][][]byte{scattered, random} {
+		cd := New(&Options{Reduced: true})
+		for i, key := range keys {
+			tt.Nil(t, cd.Insert(key, i))
+		}
+		capacity := cd.capacity
+
+		reclaimed, err := cd.Compact()
+		tt.Nil(t, err)
+		tt.True(t, reclaimed >= 0)
+		tt.True(t, cd.capacity <= capacity)
+		tt.Nil(t, cd.Verify())
+		for i, key := range keys {
+			val, err := cd.Get(key)
+			tt.Nil(t, err)
+			tt.Equal(t, i, val)
+		}
+	}
+}
+
+func TestCompactMMapCounts(t *testing.T) {
+	cd := New(&Options{Counts: true, UseMMap: true, MMapPath: t.TempDir()})
+	defer cd.Close()
+	for i := 0; i < 3000; i++ {
+		tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("song-%d", i)), i))
+	}
+	for i := 0; i < 3000; i += 3 {
+		tt.Nil(t, cd.Delete([]byte(fmt.Sprintf("song-%d", i))))
+	}
+
+	_, err := cd.Compact()
+	tt.Nil(t, err)
+	tt.Equal(t, cd.capacity, len(cd.counts))
+	tt.Nil(t, cd.Verify())
+	rank, err := cd.Rank([]byte("song-1"))
+	tt.Nil(t, err)
+	key, err := cd.Select(rank)
+	tt.Nil(t, err)
+	tt.Equal(t, "song-1", string(key))
+}
@@ -0,0 +1,49 @@
+package gocedar
+
+import "errors"
+
+// ErrNotReduced Tail needs a reduced trie
+var ErrNotReduced = errors.New("cedar: tail needs a reduced trie")
+
+// Convert returns a trie made by New(&dst) holding the keys and the values of
+// the trie, with their payloads, posting lists and the keys kept for Key, in
+// the reduced encoding or not. The keys are stored as they are, Binary and the
+// Normalizer are taken from the trie. To convert a mmap trie, `dst` may point
+// to another directory, which must be empty.
+func (cd *Cedar) Convert(reduced bool, dst Options) (*Cedar, error) {
+	if !reduced && dst.Tail {
+		return nil, ErrNotReduced
+	}
+	dst.Reduced = reduced
+	dst.Binary, dst.Normalizer = cd.binary, cd.normalize
+
+	out := New(&dst)
+	if out.LoadSize > 0 {
+		out.Close()
+		return nil, ErrNotEmpty
+	}
+
+	open := [...]func() *payloadStore{out.payloads, out.postingLists, out.originKeys}
+	err := cd.walk(0, nil, func(key []byte, val int) error {
+		to := out.insert(key, val)
+
+		from, err := cd.valueNode(key)
+		if err != nil {
+			return err
+		}
+		for i, p := range cd.stores() {
+			if p == nil || p.ref(from) == 0 {
+				continue
+			}
+			q := open[i]()
+			q.setRef(to, q.put(0, p.get(p.ref(from))))
+		}
+		return nil
+	})
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	return out, nil
+}
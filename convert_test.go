@@ -0,0 +1,79 @@
+package gocedar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	cd := New(&Options{Reduced: true, UseMMap: true, MMapPath: dir, Binary: true})
+	for i := 0; i < 500; i++ {
+		tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("k\x00%d", i)), i-250))
+	}
+	tt.Nil(t, cd.PutPayload([]byte("k\x007"), []byte("seven")))
+	tt.Nil(t, cd.Add([]byte("k\x008"), 42))
+
+	nr, err := cd.Convert(false, Options{})
+	tt.Nil(t, err)
+	tt.False(t, nr.Reduced)
+	tt.Nil(t, nr.Verify())
+	tt.Equal(t, 500, nr.Len())
+	val, err := nr.Get([]byte("k\x00100"))
+	tt.Nil(t, err)
+	tt.Equal(t, -150, val)
+	data, err := nr.GetPayload([]byte("k\x007"))
+	tt.Nil(t, err)
+	tt.Equal(t, "seven", string(data))
+	ids, err := nr.Values([]byte("k\x008"))
+	tt.Nil(t, err)
+	tt.Equal(t, []int{42}, ids)
+
+	_, err = nr.Convert(false, Options{Tail: true})
+	tt.Equal(t, ErrNotReduced, err)
+	counted, err := nr.Convert(false, Options{Counts: true})
+	tt.Nil(t, err)
+	tt.False(t, counted.Reduced)
+	rank, err := counted.Rank([]byte("k\x00100"))
+	tt.Nil(t, err)
+	tt.Equal(t, 3, rank)
+	back, err := nr.Convert(true, Options{Tail: true})
+	tt.Nil(t, err)
+	tt.True(t, back.Reduced)
+	tt.Equal(t, 500, back.Len())
+	tt.Nil(t, back.Verify())
+
+	// the mmap trie is loaded only in the encoding it was built with.
+	cd.Close()
+	func() {
+		defer func() { tt.NotNil(t, recover()) }()
+		New(&Options{UseMMap: true, MMapPath: dir})
+	}()
+	cd = New(&Options{Reduced: true, Counts: true, UseMMap: true, MMapPath: dir})
+	rank, err = cd.Rank([]byte("k\x00100"))
+	tt.Nil(t, err)
+	tt.Equal(t, 3, rank)
+	cd.Close()
+
+	dir = t.TempDir()
+	cd = New(&Options{UseMMap: true, MMapPath: dir})
+	tt.Nil(t, cd.Insert([]byte("plain"), 1))
+	cd.Close()
+	for _, o := range []Options{{Reduced: true}, {Tail: true}} {
+		func() {
+			defer func() { tt.NotNil(t, recover()) }()
+			o.UseMMap, o.MMapPath = true, dir
+			New(&o)
+		}()
+	}
+	cd = New(&Options{Counts: true, UseMMap: true, MMapPath: dir})
+	defer cd.Close()
+	tt.False(t, cd.Reduced)
+	rank, err = cd.Rank([]byte("plain"))
+	tt.Nil(t, err)
+	tt.Equal(t, 0, rank)
+	tt.Equal(t, 1, cd.CountPrefix([]byte("pl")))
+	tt.Nil(t, cd.Verify())
+}
@@ -0,0 +1,166 @@
+package gocedar
+
+import "errors"
+
+// ErrStale the trie changed since the cursor was made
+var ErrStale = errors.New("cedar: stale cursor")
+
+// Cursor walks down the trie one byte at a time, for the callers matching
+// the keys incrementally. It walks the keys as stored, the Normalizer is not
+// applied to the bytes. Unlike the node ids of `Jump`, which may silently
+// point to another node once `resolve` moves it, a Cursor fails with ErrStale
+// after the trie takes or releases a node.
+type Cursor struct {
+	cd   *Cedar
+	node int
+	pos  int // the bytes of the tail of `node` passed, with Tail
+	key  []byte
+	gen  uint64
+}
+
+// Cursor returns a cursor at the root of the trie.
+func (cd *Cedar) Cursor() *Cursor {
+	return &Cursor{cd: cd, gen: cd.gen}
+}
+
+func (c *Cursor) check() error {
+	if c.gen != c.cd.gen {
+		return ErrStale
+	}
+	return nil
+}
+
+// Step moves the cursor down by `label`, it returns ErrNoKey and stays if no
+// key goes on with `label`.
+func (c *Cursor) Step(label byte) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+
+	labels := []byte{label}
+	if c.cd.binary {
+		labels = escapeKey(labels)
+	}
+
+	node, pos := c.node, c.pos
+	for _, l := range labels {
+		var ok bool
+		if node, pos, ok = c.cd.step(node, pos, l); !ok {
+			return ErrNoKey
+		}
+	}
+
+	c.node, c.pos = node, pos
+	c.key = append(c.key, label)
+	return nil
+}
+
+// StepBytes moves the cursor down by every byte of `key`, it returns ErrNoKey
+// and stays where it was if no key goes on with `key`.
+func (c *Cursor) StepBytes(key []byte) error {
+	node, pos, n := c.node, c.pos, len(c.key)
+	for _, label := range key {
+		if err := c.Step(label); err != nil {
+			c.node, c.pos, c.key = node, pos, c.key[:n]
+			return err
+		}
+	}
+	return nil
+}
+
+// Value returns the value of the key the cursor is at.
+func (c *Cursor) Value() (int, error) {
+	if err := c.check(); err != nil {
+		return 0, err
+	}
+
+	if c.cd.tail != nil {
+		if v := c.cd.array[c.node].baseV; v >= 0 && v != ValLimit {
+			if suffix, _ := c.cd.tail.entry(v); c.pos < len(suffix) {
+				return 0, ErrNoVal
+			}
+		}
+	}
+	return c.cd.Value(c.node)
+}
+
+// Children returns the bytes the cursor may `Step` by, in order.
+func (c *Cursor) Children() ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+
+	labels := c.cd.labels(c.node, c.pos)
+	if !c.cd.binary {
+		return labels, nil
+	}
+
+	// the escaped bytes are one level further down.
+	var out []byte
+	for _, l := range labels {
+		if l != escapeByte {
+			out = append(out, l)
+			continue
+		}
+		node, pos, _ := c.cd.step(c.node, c.pos, l)
+		for _, e := range c.cd.labels(node, pos) {
+			out = append(out, e-1)
+		}
+	}
+	return out, nil
+}
+
+// Depth returns the number of bytes the cursor went down by.
+func (c *Cursor) Depth() int {
+	return len(c.key)
+}
+
+// Key returns the bytes the cursor went down by.
+func (c *Cursor) Key() []byte {
+	return append([]byte(nil), c.key...)
+}
+
+// step follows the child labeled `label` of the node `from`, with `pos` bytes
+// of its tail passed. It is the single byte `jump` able to stop inside a tail.
+func (cd *Cedar) step(from, pos int, label byte) (int, int, bool) {
+	v := cd.array[from].baseV
+	if cd.Reduced && v >= 0 {
+		if cd.tail == nil || v == ValLimit {
+			return from, pos, false
+		}
+		suffix, _ := cd.tail.entry(v)
+		if pos < len(suffix) && suffix[pos] == label {
+			return from, pos + 1, true
+		}
+		return from, pos, false
+	}
+
+	base := cd.array[from].base(cd.Reduced)
+	to := base ^ int(label)
+	if base < 0 || label == 0 || to >= cd.size || cd.array[to].check != from {
+		return from, pos, false
+	}
+	return to, 0, true
+}
+
+// labels returns the labels `step` may follow from the node `from`.
+func (cd *Cedar) labels(from, pos int) []byte {
+	if v := cd.array[from].baseV; cd.Reduced && v >= 0 {
+		if cd.tail == nil || v == ValLimit {
+			return nil
+		}
+		if suffix, _ := cd.tail.entry(v); pos < len(suffix) {
+			return []byte{suffix[pos]}
+		}
+		return nil
+	}
+
+	var labels []byte
+	cd.children(from, func(label byte, _ int) bool {
+		if label != 0 {
+			labels = append(labels, label)
+		}
+		return true
+	})
+	return labels
+}
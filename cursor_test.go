@@ -0,0 +1,68 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestCursor(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	for i, key := range []string{"a", "ab", "abc", "abd", "b"} {
+		tt.Nil(t, cd.Insert([]byte(key), i))
+	}
+
+	c := cd.Cursor()
+	children, err := c.Children()
+	tt.Nil(t, err)
+	tt.Equal(t, "ab", string(children))
+	_, err = c.Value()
+	tt.NotNil(t, err)
+
+	tt.Nil(t, c.Step('a'))
+	val, err := c.Value()
+	tt.Nil(t, err)
+	tt.Equal(t, 0, val)
+
+	tt.Equal(t, ErrNoKey, c.StepBytes([]byte("bx")))
+	tt.Equal(t, "a", string(c.Key()))
+	tt.Nil(t, c.StepBytes([]byte("bd")))
+	tt.Equal(t, 3, c.Depth())
+	val, err = c.Value()
+	tt.Nil(t, err)
+	tt.Equal(t, 3, val)
+
+	// the cursor fails once the trie takes a node.
+	tt.Nil(t, cd.Insert([]byte("abde"), 5))
+	_, err = c.Value()
+	tt.Equal(t, ErrStale, err)
+	tt.Equal(t, ErrStale, c.Step('e'))
+}
+
+func TestCursorTail(t *testing.T) {
+	cd := New(&Options{Tail: true, Binary: true})
+	tt.Nil(t, cd.Insert([]byte("http://a.com"), 1))
+	tt.Nil(t, cd.Insert([]byte{'x', 0, 1}, 2))
+
+	c := cd.Cursor()
+	tt.Nil(t, c.StepBytes([]byte("http://a")))
+	children, err := c.Children()
+	tt.Nil(t, err)
+	tt.Equal(t, ".", string(children))
+	_, err = c.Value()
+	tt.Equal(t, ErrNoVal, err)
+	tt.Nil(t, c.StepBytes([]byte(".com")))
+	val, err := c.Value()
+	tt.Nil(t, err)
+	tt.Equal(t, 1, val)
+
+	c = cd.Cursor()
+	tt.Nil(t, c.Step('x'))
+	children, err = c.Children()
+	tt.Nil(t, err)
+	tt.Equal(t, []byte{0}, children)
+	tt.Nil(t, c.StepBytes([]byte{0, 1}))
+	val, err = c.Value()
+	tt.Nil(t, err)
+	tt.Equal(t, 2, val)
+}
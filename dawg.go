@@ -0,0 +1,463 @@
+package gocedar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"unsafe"
+)
+
+const (
+	dawgFinal = 1 << 8 // the flag in `check` marking the target state as final
+	dawgSkip  = 9      // the shift in `check` of the number of keys skipped by the edge
+)
+
+// DAWG is a minimal acyclic automaton for a static set of keys: the states
+// with the same suffixes are merged, and the automaton is stored in the
+// base/check arrays the same way as Cedar.
+//
+// Since a state may have many parents, a node is an edge rather than a state:
+// `baseV` is the base of the target state (-1 if it has no edge), and `check`
+// holds the label, the final flag of the target and the number of keys that
+// sort before the edge among the keys of the source state. Summing the latter
+// along a key gives its rank in the sorted keys, which indexes the values.
+type DAWG struct {
+	array  []Node
+	nInfos []NInfo // sibling: the next label of the source state, child: the first label of the target
+
+	root  int  // the base of the root state
+	child byte // the first label of the root
+	num   int  // the number of keys
+	vals  []int
+}
+
+// dawgState is a state of the automaton under construction.
+type dawgState struct {
+	final  bool
+	labels []byte
+	to     []*dawgState
+	count  int // the number of keys accepted from this state
+	id     int
+}
+
+// BuildDAWG constructs a DAWG from `keys`. `vals` may be nil, in which case
+// the value of a key is its rank in the sorted keys, a minimal perfect hash.
+// Duplicated keys keep the last value, and the empty key is rejected.
+func BuildDAWG(keys [][]byte, vals []int) (*DAWG, error) {
+	if vals != nil && len(vals) != len(keys) {
+		return nil, ErrInvalidVal
+	}
+
+	order := make([]int, len(keys))
+	for i := range order {
+		if len(keys[i]) == 0 {
+			return nil, ErrInvalidKey
+		}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(keys[order[i]], keys[order[j]]) < 0
+	})
+
+	d := &DAWG{}
+	b := &dawgBuilder{register: make(map[string]*dawgState)}
+	root := &dawgState{}
+	path := []*dawgState{root}
+	var prev []byte
+	for n, i := range order {
+		key := keys[i]
+		if n+1 < len(order) && bytes.Equal(key, keys[order[n+1]]) {
+			continue
+		}
+
+		p := 0
+		for p < len(prev) && p < len(key) && prev[p] == key[p] {
+			p++
+		}
+		path = b.minimize(path, p)
+		for _, c := range key[p:] {
+			s := &dawgState{}
+			last := path[len(path)-1]
+			last.labels = append(last.labels, c)
+			last.to = append(last.to, s)
+			path = append(path, s)
+		}
+		path[len(path)-1].final = true
+		prev = key
+
+		if vals != nil {
+			d.vals = append(d.vals, vals[i])
+		}
+	}
+	b.minimize(path, 0)
+	root = b.add(root)
+
+	d.num = root.count
+	d.child = firstLabel(root)
+	d.root = d.layout(b.states, root)
+
+	return d, nil
+}
+
+// BuildDAWG constructs a DAWG holding the keys and the values of the trie.
+func (cd *Cedar) BuildDAWG() (*DAWG, error) {
+	var (
+		keys [][]byte
+		vals []int
+	)
+	err := cd.walk(0, nil, func(key []byte, val int) error {
+		keys = append(keys, append([]byte(nil), cd.unescape(key)...))
+		vals = append(vals, val)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildDAWG(keys, vals)
+}
+
+type dawgBuilder struct {
+	register map[string]*dawgState
+	states   []*dawgState
+	sig      []byte
+}
+
+// minimize replaces the states of `path` deeper than `p` by their equivalent
+// registered states, and returns the path cut to `p`.
+func (b *dawgBuilder) minimize(path []*dawgState, p int) []*dawgState {
+	for i := len(path) - 1; i > p; i-- {
+		parent := path[i-1]
+		parent.to[len(parent.to)-1] = b.add(path[i])
+	}
+	return path[:p+1]
+}
+
+// add returns the registered state equivalent to `s`, registering `s` if
+// there is none. The targets of `s` must be registered already.
+func (b *dawgBuilder) add(s *dawgState) *dawgState {
+	b.sig = b.sig[:0]
+	if s.final {
+		b.sig = append(b.sig, 1)
+	}
+	var id [binary.MaxVarintLen64]byte
+	for i, c := range s.labels {
+		b.sig = append(b.sig, c)
+		b.sig = append(b.sig, id[:binary.PutUvarint(id[:], uint64(s.to[i].id))]...)
+	}
+
+	if r, ok := b.register[string(b.sig)]; ok {
+		return r
+	}
+
+	if s.final {
+		s.count = 1
+	}
+	for _, t := range s.to {
+		s.count += t.count
+	}
+	s.id = len(b.states)
+	b.states = append(b.states, s)
+	b.register[string(b.sig)] = s
+
+	return s
+}
+
+func firstLabel(s *dawgState) byte {
+	if len(s.labels) == 0 {
+		return 0
+	}
+	return s.labels[0]
+}
+
+// layout places the edges of every state in the array, and returns the base
+// of the root. The free nodes are found the same way as Cedar does, by a Cedar
+// used as an allocator. Each state gets its own base, so that the label in
+// `check` is enough to tell the owner of an edge: the node `base ^ 0` of every
+// state is taken, by its edge labeled 0 or left empty.
+func (d *DAWG) layout(states []*dawgState, root *dawgState) int {
+	// the first block of the allocator holds its root, the edges are moved
+	// down by one block at last.
+	alloc := New(&Options{})
+	bases := make([]int, len(states))
+	children := make([]byte, 0, 257)
+
+	place := func(s *dawgState) int {
+		if len(s.labels) == 0 {
+			return -1
+		}
+		children = append(children[:0], s.labels...)
+		if children[0] != 0 {
+			children = append([]byte{0}, children...)
+		}
+
+		e := 0
+		if len(children) == 1 {
+			e = alloc.findPlace()
+		} else {
+			e = alloc.findPlaces(children)
+		}
+		base := e ^ int(children[0])
+		for _, c := range children {
+			alloc.popENode(base, 0, c)
+		}
+		return base - 256
+	}
+
+	// place the states from the root down, so that the edges close to the
+	// root stay close to each other.
+	queue := []*dawgState{root}
+	seen := make([]bool, len(states))
+	seen[root.id] = true
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		bases[s.id] = place(s)
+		for _, t := range s.to {
+			if !seen[t.id] {
+				seen[t.id] = true
+				queue = append(queue, t)
+			}
+		}
+	}
+
+	n := 0
+	for _, s := range states {
+		for _, c := range s.labels {
+			if e := bases[s.id] ^ int(c); e >= n {
+				n = e + 1
+			}
+		}
+	}
+	d.array = make([]Node, n)
+	d.nInfos = make([]NInfo, n)
+	for i := range d.array {
+		d.array[i] = Node{baseV: -1, check: -1}
+	}
+	for _, s := range states {
+		skip := 0
+		if s.final {
+			skip = 1
+		}
+		for i, c := range s.labels {
+			t := s.to[i]
+			check := int(c) | skip<<dawgSkip
+			if t.final {
+				check |= dawgFinal
+			}
+
+			e := bases[s.id] ^ int(c)
+			d.array[e] = Node{baseV: bases[t.id], check: check}
+			d.nInfos[e].child = firstLabel(t)
+			if i+1 < len(s.labels) {
+				d.nInfos[e].sibling = s.labels[i+1]
+			}
+			skip += t.count
+		}
+	}
+
+	return bases[root.id]
+}
+
+// Len returns the number of keys.
+func (d *DAWG) Len() int {
+	return d.num
+}
+
+// edge returns the node of the edge labeled `label` from the state with `base`.
+func (d *DAWG) edge(base int, label byte) (int, bool) {
+	if base < 0 {
+		return 0, false
+	}
+	e := base ^ int(label)
+	if e >= len(d.array) || d.array[e].check < 0 || byte(d.array[e].check) != label {
+		return 0, false
+	}
+	return e, true
+}
+
+// Rank returns the rank of `key` in the sorted keys.
+func (d *DAWG) Rank(key []byte) (int, error) {
+	base, rank, final := d.root, 0, false
+	for _, c := range key {
+		e, ok := d.edge(base, c)
+		if !ok {
+			return 0, ErrNoKey
+		}
+		rank += d.array[e].check >> dawgSkip
+		final = d.array[e].check&dawgFinal != 0
+		base = d.array[e].baseV
+	}
+
+	if !final {
+		return 0, ErrNoKey
+	}
+	return rank, nil
+}
+
+// Value returns the value of the key with `rank`.
+func (d *DAWG) Value(rank int) (int, error) {
+	if rank < 0 || rank >= d.num {
+		return 0, ErrNoVal
+	}
+	if d.vals == nil {
+		return rank, nil
+	}
+	return d.vals[rank], nil
+}
+
+// Get returns the value of `key`.
+func (d *DAWG) Get(key []byte) (int, error) {
+	rank, err := d.Rank(key)
+	if err != nil {
+		return 0, err
+	}
+	return d.Value(rank)
+}
+
+// Key returns the key with `rank`, following at each state the edge whose
+// keys cover the rank.
+func (d *DAWG) Key(rank int) ([]byte, error) {
+	if rank < 0 || rank >= d.num {
+		return nil, ErrNoKey
+	}
+
+	var key []byte
+	base, c := d.root, d.child
+	for {
+		// look for the last edge skipping no more keys than the rank.
+		e, _ := d.edge(base, c)
+		for c = d.nInfos[e].sibling; c != 0; c = d.nInfos[e].sibling {
+			next, _ := d.edge(base, c)
+			if d.array[next].check>>dawgSkip > rank {
+				break
+			}
+			e = next
+		}
+
+		// the key of the target state itself comes first among its keys.
+		rank -= d.array[e].check >> dawgSkip
+		key = append(key, byte(d.array[e].check))
+		if rank == 0 && d.array[e].check&dawgFinal != 0 {
+			return key, nil
+		}
+		base, c = d.array[e].baseV, d.nInfos[e].child
+	}
+}
+
+// PrefixMatch returns the ranks of the keys which are prefixes of `key`.
+func (d *DAWG) PrefixMatch(key []byte, n ...int) (ranks []int) {
+	num := 0
+	if len(n) > 0 {
+		num = n[0]
+	}
+
+	base, rank := d.root, 0
+	for _, c := range key {
+		e, ok := d.edge(base, c)
+		if !ok {
+			return
+		}
+		rank += d.array[e].check >> dawgSkip
+		if d.array[e].check&dawgFinal != 0 {
+			ranks = append(ranks, rank)
+			num--
+			if num == 0 {
+				return
+			}
+		}
+		base = d.array[e].baseV
+	}
+
+	return
+}
+
+// PrefixPredict returns the ranks of the keys which have `key` as their prefix,
+// since the keys are sorted they are consecutive.
+func (d *DAWG) PrefixPredict(key []byte, n ...int) (ranks []int) {
+	begin, end := 0, d.num
+	base := d.root
+	for _, c := range key {
+		e, ok := d.edge(base, c)
+		if !ok {
+			return
+		}
+
+		// the keys below the edge end where the next edge begins.
+		if sibling := d.nInfos[e].sibling; sibling != 0 {
+			next, _ := d.edge(base, sibling)
+			end = begin + d.array[next].check>>dawgSkip
+		}
+		begin += d.array[e].check >> dawgSkip
+		base = d.array[e].baseV
+	}
+
+	if len(n) > 0 && n[0] > 0 && begin+n[0] < end {
+		end = begin + n[0]
+	}
+	for rank := begin; rank < end; rank++ {
+		ranks = append(ranks, rank)
+	}
+	return
+}
+
+// WriteTo writes the DAWG to `w`, in the byte order of the machine as the mmap files.
+func (d *DAWG) WriteTo(w io.Writer) (int64, error) {
+	head := []int{d.root, int(d.child), d.num, len(d.array), len(d.vals)}
+	var n int64
+	for _, b := range [][]byte{
+		intsBytes(head), nodesBytes(d.array), nInfosBytes(d.nInfos), intsBytes(d.vals),
+	} {
+		m, err := w.Write(b)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// ReadDAWG reads a DAWG written by `WriteTo`.
+func ReadDAWG(r io.Reader) (*DAWG, error) {
+	head := make([]int, 5)
+	if _, err := io.ReadFull(r, intsBytes(head)); err != nil {
+		return nil, err
+	}
+
+	d := &DAWG{root: head[0], child: byte(head[1]), num: head[2]}
+	d.array = make([]Node, head[3])
+	d.nInfos = make([]NInfo, head[3])
+	if head[4] > 0 {
+		d.vals = make([]int, head[4])
+	}
+	for _, b := range [][]byte{nodesBytes(d.array), nInfosBytes(d.nInfos), intsBytes(d.vals)} {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+func intsBytes(s []int) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(0)))
+}
+
+func nodesBytes(s []Node) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*nodeSize)
+}
+
+func nInfosBytes(s []NInfo) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*nInfoSize)
+}
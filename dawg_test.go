@@ -0,0 +1,74 @@
+package gocedar
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestDAWG(t *testing.T) {
+	keys := [][]byte{
+		[]byte("love song remix"), []byte("sad song remix"), []byte("love song"),
+		[]byte("sad song"), []byte("love"), []byte("梦 live version"), []byte("梦"),
+	}
+	vals := []int{10, 20, 30, 40, 50, 60, 70}
+
+	d, err := BuildDAWG(keys, vals)
+	tt.Nil(t, err)
+	tt.Equal(t, 7, d.Len())
+	for i, key := range keys {
+		val, err := d.Get(key)
+		tt.Nil(t, err)
+		tt.Equal(t, vals[i], val)
+	}
+	_, err = d.Get([]byte("love so"))
+	tt.NotNil(t, err)
+
+	// the ranks follow the sorted keys.
+	for rank := 0; rank < d.Len(); rank++ {
+		key, err := d.Key(rank)
+		tt.Nil(t, err)
+		r, err := d.Rank(key)
+		tt.Nil(t, err)
+		tt.Equal(t, rank, r)
+	}
+	rank, _ := d.Rank([]byte("love"))
+	tt.Equal(t, 0, rank)
+
+	tt.Equal(t, 3, len(d.PrefixPredict([]byte("love"))))
+	tt.Equal(t, 2, len(d.PrefixMatch([]byte("sad song remix"))))
+
+	cd := New(&Options{Reduced: true})
+	for i, key := range keys {
+		tt.Nil(t, cd.Insert(key, vals[i]))
+	}
+	d, err = cd.BuildDAWG()
+	tt.Nil(t, err)
+
+	var buf bytes.Buffer
+	_, err = d.WriteTo(&buf)
+	tt.Nil(t, err)
+	d, err = ReadDAWG(&buf)
+	tt.Nil(t, err)
+	val, err := d.Get([]byte("sad song remix"))
+	tt.Nil(t, err)
+	tt.Equal(t, 20, val)
+
+	// the states are laid out by the free node search of Cedar.
+	keys = keys[:0]
+	for i := 0; i < 5000; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("%x-%d", i*7919, i%13)))
+	}
+	d, err = BuildDAWG(keys, nil)
+	tt.Nil(t, err)
+	tt.Equal(t, len(keys), d.Len())
+	for _, key := range keys {
+		rank, err := d.Rank(key)
+		tt.Nil(t, err)
+		k, err := d.Key(rank)
+		tt.Nil(t, err)
+		tt.Equal(t, string(key), string(k))
+	}
+}
@@ -0,0 +1,298 @@
+package gocedar
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrConflict the trie does not hold what the change was made from
+var ErrConflict = errors.New("cedar: change does not apply")
+
+// Op is the kind of a Change.
+type Op byte
+
+// The kinds of Change, as written in a patch.
+const (
+	Added   Op = '+'
+	Removed Op = '-'
+	Changed Op = '~'
+)
+
+// Change is a difference between two tries. Old is the value of a Removed or
+// Changed key, New the value of an Added or Changed one. The key is as stored,
+// changed by the Normalizer if any.
+type Change struct {
+	Op       Op
+	Key      []byte
+	Old, New int
+}
+
+// String returns the change as a line of a patch: the Op, the quoted key and
+// the values, separated by spaces.
+func (c Change) String() string {
+	key := strconv.Quote(string(c.Key))
+	switch c.Op {
+	case Added:
+		return fmt.Sprintf("%c %s %d", c.Op, key, c.New)
+	case Removed:
+		return fmt.Sprintf("%c %s %d", c.Op, key, c.Old)
+	}
+	return fmt.Sprintf("%c %s %d %d", c.Op, key, c.Old, c.New)
+}
+
+// ParseChange parses a line written by Change.String.
+func ParseChange(line string) (Change, error) {
+	var c Change
+	if len(line) < 2 || line[1] != ' ' {
+		return c, fmt.Errorf("cedar: invalid change %q", line)
+	}
+	c.Op = Op(line[0])
+
+	quoted, err := strconv.QuotedPrefix(line[2:])
+	if err != nil {
+		return c, fmt.Errorf("cedar: invalid change %q: %w", line, err)
+	}
+	key, _ := strconv.Unquote(quoted)
+	c.Key = []byte(key)
+
+	vals := strings.Fields(line[2+len(quoted):])
+	n := 1
+	if c.Op == Changed {
+		n = 2
+	}
+	if len(vals) != n || c.Op != Added && c.Op != Removed && c.Op != Changed {
+		return c, fmt.Errorf("cedar: invalid change %q", line)
+	}
+
+	nums := make([]int, n)
+	for i, v := range vals {
+		if nums[i], err = strconv.Atoi(v); err != nil {
+			return c, fmt.Errorf("cedar: invalid change %q: %w", line, err)
+		}
+	}
+	switch c.Op {
+	case Added:
+		c.New = nums[0]
+	case Removed:
+		c.Old = nums[0]
+	default:
+		c.Old, c.New = nums[0], nums[1]
+	}
+	return c, nil
+}
+
+// Apply makes the change to the trie. It returns ErrConflict and leaves the
+// trie as it is if the key is there for Added, or does not hold Old for
+// Removed and Changed.
+func (cd *Cedar) Apply(c Change) error {
+	val, err := cd.Get(c.Key)
+	exists := err == nil
+	switch {
+	case c.Op == Added && exists,
+		c.Op != Added && (!exists || val != c.Old):
+		return ErrConflict
+	}
+
+	if c.Op == Removed {
+		return cd.Delete(c.Key)
+	}
+	return cd.Insert(c.Key, c.New)
+}
+
+// Patch applies the changes read from `r`, one per line as written by
+// DiffIter.WriteTo, stopping at the first one failing.
+func (cd *Cedar) Patch(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		c, err := ParseChange(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if err = cd.Apply(c); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// DiffIter walks the changes from one trie to another in the order of the
+// keys, see Diff.
+type DiffIter struct {
+	a, b   *keyIter
+	change Change
+	err    error
+}
+
+// Diff returns an iterator of the changes turning the trie `a` into `b`,
+// walking both of them side by side in the order of the keys. The tries must
+// not change during the walk, the iterator stops with ErrStale then.
+//
+//	it := Diff(a, b)
+//	for it.Next() {
+//		fmt.Println(it.Change())
+//	}
+//	err := it.Err()
+func Diff(a, b *Cedar) *DiffIter {
+	it := &DiffIter{a: newKeyIter(a, 0, nil), b: newKeyIter(b, 0, nil)}
+	it.a.next()
+	it.b.next()
+	return it
+}
+
+// Next moves to the next change, it returns false at the end or on an error.
+func (it *DiffIter) Next() bool {
+	a, b := it.a, it.b
+	for it.err == nil && (a.ok || b.ok) {
+		if it.err = a.check(); it.err == nil {
+			it.err = b.check()
+		}
+		if it.err != nil {
+			return false
+		}
+
+		ka, kb := a.cd.unescape(a.key), b.cd.unescape(b.key)
+		cmp := bytes.Compare(ka, kb)
+		switch {
+		case !b.ok || a.ok && cmp < 0:
+			it.change = Change{Op: Removed, Key: append([]byte(nil), ka...), Old: a.val}
+			a.next()
+		case !a.ok || cmp > 0:
+			it.change = Change{Op: Added, Key: append([]byte(nil), kb...), New: b.val}
+			b.next()
+		default:
+			it.change = Change{Op: Changed, Key: append([]byte(nil), ka...), Old: a.val, New: b.val}
+			a.next()
+			b.next()
+			if it.change.Old == it.change.New {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Change returns the change Next moved to.
+func (it *DiffIter) Change() Change {
+	return it.change
+}
+
+// Err returns the error stopping the iterator, nil at the end.
+func (it *DiffIter) Err() error {
+	return it.err
+}
+
+// WriteTo writes the changes left as a patch, one per line, which Cedar.Patch
+// applies to `a` to make it `b`.
+func (it *DiffIter) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for it.Next() {
+		n, err := fmt.Fprintln(w, it.Change())
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, it.Err()
+}
+
+// keyIter yields the keys of a trie and their values in order, one at a time.
+type keyIter struct {
+	cd     *Cedar
+	stack  []keyFrame
+	key    []byte
+	prefix []byte // the stored prefix of the keys yielded
+	val    int
+	ok     bool
+	gen    uint64
+}
+
+// keyFrame is a node on the path of a keyIter, with its children left.
+type keyFrame struct {
+	edges []Edge
+	depth int // the length of the key leading to the node
+}
+
+// newKeyIter returns an iterator of the keys below the node `from`, which the
+// stored `key` leads to.
+func newKeyIter(cd *Cedar, from int, key []byte) *keyIter {
+	it := &keyIter{cd: cd, gen: cd.gen, key: append([]byte(nil), key...)}
+	if from != 0 && cd.Reduced && cd.array[from].baseV >= 0 {
+		// the leaf is the only child of a node of its own.
+		n := len(key) - 1
+		it.key = it.key[:n]
+		it.stack = []keyFrame{{edges: []Edge{{Label: key[n], To: from}}, depth: n}}
+		return it
+	}
+
+	it.push(from)
+	return it
+}
+
+func (it *keyIter) check() error {
+	if it.gen != it.cd.gen {
+		return ErrStale
+	}
+	return nil
+}
+
+// push adds the node `from` to the path, with all of its children.
+func (it *keyIter) push(from int) {
+	var edges []Edge
+	it.cd.children(from, func(label byte, to int) bool {
+		edges = append(edges, Edge{Label: label, To: to})
+		return true
+	})
+	it.stack = append(it.stack, keyFrame{edges: edges, depth: len(it.key)})
+}
+
+// next moves to the next key, setting `ok` to false at the end.
+func (it *keyIter) next() {
+	cd := it.cd
+	for len(it.stack) > 0 {
+		f := &it.stack[len(it.stack)-1]
+		if len(f.edges) == 0 {
+			it.stack = it.stack[:len(it.stack)-1]
+			continue
+		}
+		e := f.edges[0]
+		f.edges = f.edges[1:]
+		it.key = it.key[:f.depth]
+
+		v := cd.array[e.To].baseV
+		switch {
+		case e.Label == 0:
+			if v == ValLimit {
+				continue
+			}
+			it.val = cd.leafValue(v)
+		case cd.Reduced && v >= 0:
+			if v == ValLimit {
+				continue
+			}
+			it.key, it.val = append(it.key, e.Label), v
+			if cd.tail != nil {
+				suffix, val := cd.tail.entry(v)
+				it.key, it.val = append(it.key, suffix...), val
+			}
+		default:
+			it.key = append(it.key, e.Label)
+			it.push(e.To)
+			continue
+		}
+
+		// a tail may go on from the prefix, or part from it.
+		if it.ok = bytes.HasPrefix(it.key, it.prefix); it.ok {
+			return
+		}
+	}
+	it.ok = false
+}
@@ -0,0 +1,49 @@
+package gocedar
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestDiff(t *testing.T) {
+	a := New(&Options{Reduced: true})
+	b := New(&Options{Tail: true, Binary: true})
+	for key, val := range map[string]int{"apple": 1, "app": 2, "banana": 3, "cherry": 4} {
+		tt.Nil(t, a.Insert([]byte(key), val))
+	}
+	for key, val := range map[string]int{"apple": 1, "app": 5, "cherry": 4, "date\x00": -6} {
+		tt.Nil(t, b.Insert([]byte(key), val))
+	}
+
+	var changes []string
+	it := Diff(a, b)
+	for it.Next() {
+		changes = append(changes, it.Change().String())
+	}
+	tt.Nil(t, it.Err())
+	tt.Equal(t, []string{`~ "app" 2 5`, `- "banana" 3`, `+ "date\x00" -6`}, changes)
+
+	c, err := ParseChange(changes[2])
+	tt.Nil(t, err)
+	tt.Equal(t, Change{Op: Added, Key: []byte("date\x00"), New: -6}, c)
+	_, err = ParseChange(`? "app" 1`)
+	tt.NotNil(t, err)
+
+	// the patch turns a copy of `a` into `b`, and applies only once.
+	var patch bytes.Buffer
+	_, err = Diff(a, b).WriteTo(&patch)
+	tt.Nil(t, err)
+	p := New(&Options{Reduced: true, Binary: true})
+	tt.Nil(t, Merge(p, a, nil))
+	tt.Nil(t, p.Patch(bytes.NewReader(patch.Bytes())))
+	tt.False(t, Diff(p, b).Next())
+	tt.Equal(t, ErrConflict, p.Patch(bytes.NewReader(patch.Bytes())))
+
+	it = Diff(a, b)
+	tt.True(t, it.Next())
+	tt.Nil(t, a.Insert([]byte("fig"), 7))
+	tt.False(t, it.Next())
+	tt.Equal(t, ErrStale, it.Err())
+}
@@ -1,6 +1,6 @@
 module github.com/RicardoL1u/gocedar
 
-go 1.17
+go 1.18
 
 require github.com/vcaesar/tt v0.20.0
 
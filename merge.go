@@ -0,0 +1,82 @@
+package gocedar
+
+import "bytes"
+
+// KeepLeft is the Merge policy keeping the value of the destination.
+func KeepLeft(key []byte, a, b int) int { return a }
+
+// KeepRight is the Merge policy taking the value of the source.
+func KeepRight(key []byte, a, b int) int { return b }
+
+// Sum is the Merge policy adding the values up, the sum must not be ValLimit.
+func Sum(key []byte, a, b int) int { return a + b }
+
+// Merge stores every key of `src` to `dst`. For a key in both, `policy` is
+// called with the key, the value in `dst` and the one in `src`, and the value
+// it returns is stored, a nil policy is KeepRight. An empty `dst` is built
+// from the sorted keys of `src` at once, rather than by one Insert per key.
+// The payloads and the posting lists of `src` are not merged.
+func Merge(dst, src *Cedar, policy func(key []byte, a, b int) int) error {
+	if policy == nil {
+		policy = KeepRight
+	}
+
+	var (
+		keys [][]byte
+		vals []int
+	)
+	err := src.walk(0, nil, func(key []byte, val int) error {
+		keys = append(keys, append([]byte(nil), src.original(key)...))
+		vals = append(vals, val)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the keys holding the byte 0 need Binary.
+	for _, key := range keys {
+		if !dst.binary && bytes.IndexByte(key, 0) >= 0 {
+			return ErrInvalidKey
+		}
+	}
+
+	if dst.keys == 0 && dst.normalize == nil {
+		return dst.mergeBuild(keys, vals)
+	}
+
+	for i, key := range keys {
+		val := vals[i]
+		_, err := dst.Upsert(key, func(old int, exists bool) (int, bool) {
+			if exists {
+				val = policy(key, old, val)
+			}
+			return val, true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeBuild builds the empty trie from the distinct keys and their values.
+func (cd *Cedar) mergeBuild(keys [][]byte, vals []int) error {
+	stored := make([][]byte, len(keys))
+	for i, key := range keys {
+		stored[i] = cd.escape(key)
+	}
+
+	tmp, err := Build(stored, vals, &Options{
+		Reduced:   cd.Reduced,
+		Tail:      cd.tail != nil,
+		Unordered: !cd.ordered,
+		MaxTrial:  cd.maxTrial,
+	})
+	if err != nil {
+		return err
+	}
+
+	cd.adopt(tmp)
+	return nil
+}
@@ -0,0 +1,76 @@
+package gocedar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestMerge(t *testing.T) {
+	base := New(&Options{Reduced: true})
+	tt.Nil(t, base.Insert([]byte("apple"), 1))
+	tt.Nil(t, base.Insert([]byte("banana"), 2))
+
+	tenant := New(&Options{Reduced: true})
+	tt.Nil(t, tenant.Insert([]byte("banana"), 20))
+	tt.Nil(t, tenant.Insert([]byte("cherry"), 30))
+
+	get := func(cd *Cedar, key string) int {
+		val, err := cd.Get([]byte(key))
+		tt.Nil(t, err)
+		return val
+	}
+
+	// an empty destination is built at once.
+	dst := New(&Options{Tail: true})
+	tt.Nil(t, Merge(dst, base, nil))
+	tt.Equal(t, 2, dst.Len())
+	tt.Nil(t, dst.Verify())
+	tt.Equal(t, 1, get(dst, "apple"))
+
+	// the empty key is built with the others.
+	withEmpty := New(&Options{Reduced: true})
+	tt.Nil(t, withEmpty.Insert([]byte(""), 7))
+	tt.Nil(t, withEmpty.Insert([]byte("apple"), 1))
+	dst = New(&Options{Reduced: true})
+	tt.Nil(t, Merge(dst, withEmpty, nil))
+	tt.Equal(t, 2, dst.Len())
+	tt.Equal(t, 7, get(dst, ""))
+	tt.Nil(t, dst.Verify())
+
+	// so is an empty mmap destination keeping the counts.
+	mm := New(&Options{Counts: true, UseMMap: true, MMapPath: t.TempDir()})
+	defer mm.Close()
+	big := New(&Options{Reduced: true})
+	for i := 0; i < 2000; i++ {
+		tt.Nil(t, big.Insert([]byte(fmt.Sprintf("key-%d", i)), i))
+	}
+	tt.Nil(t, Merge(mm, big, nil))
+	tt.Equal(t, 2000, mm.Len())
+	tt.Nil(t, mm.Verify())
+	tt.Equal(t, 1000, get(mm, "key-1000"))
+
+	policies := map[string]func([]byte, int, int) int{"left": KeepLeft, "right": KeepRight, "sum": Sum}
+	want := map[string]int{"left": 2, "right": 20, "sum": 22}
+	for name, policy := range policies {
+		dst, err := base.Clone(nil)
+		tt.Nil(t, err)
+		tt.Nil(t, Merge(dst, tenant, policy))
+		tt.Equal(t, 3, dst.Len())
+		tt.Equal(t, want[name], get(dst, "banana"))
+		tt.Equal(t, 30, get(dst, "cherry"))
+	}
+
+	var conflicts []string
+	tt.Nil(t, Merge(base, tenant, func(key []byte, a, b int) int {
+		conflicts = append(conflicts, string(key))
+		return a * b
+	}))
+	tt.Equal(t, []string{"banana"}, conflicts)
+	tt.Equal(t, 40, get(base, "banana"))
+
+	bin := New(&Options{Reduced: true, Binary: true})
+	tt.Nil(t, bin.Insert([]byte("a\x00b"), 1))
+	tt.Equal(t, ErrInvalidKey, Merge(base, bin, nil))
+}
@@ -21,12 +21,16 @@ const (
 	nInfoSize = int(unsafe.Sizeof(NInfo{}))
 	blockSize = int(unsafe.Sizeof(Block{}))
 	metaSize  = int(unsafe.Sizeof(MetaInfo{}))
+	extSize   = int(unsafe.Sizeof(metaExt{}))
 
 	defaultNodeNumber = 256
+	pageSize          = 4096
 
 	arrayFileName = "array"
 	blockFileName = "block" // 头部存cedar里面非slice的信息
 	nInfoFileName = "nInfo"
+	tailFileName  = "tail"
+	metaFileName  = "meta"
 	fileMode      = os.FileMode(0666)
 )
 
@@ -47,6 +51,18 @@ type MetaInfo struct {
 	maxTrial int //
 }
 
+// metaVersion is the version of metaExt written by this package, a trie
+// loaded with an older one is migrated when opened.
+const metaVersion = 1
+
+// metaExt holds the meta info added after the layout of MetaInfo in the
+// block file was fixed, for mmap it is kept in the file `meta`.
+type metaExt struct {
+	version int
+	keys    int  // the number of keys
+	binary  bool // the keys are escaped, see Options.Binary
+}
+
 type MMap struct {
 	loadSize                           int
 	mmapDir                            string
@@ -55,6 +71,7 @@ type MMap struct {
 	block                              *[defaultMaxSize >> 8]Block
 	nInfo                              *[defaultMaxSize]NInfo
 	metaInfo                           *MetaInfo
+	ext                                *region
 	arrayBytes, blockBytes, nInfoBytes []byte
 	arrayFile, blockFile, nInfoFile    *os.File
 	arrayMSize, blockMSize, nInfoMSize int
@@ -90,6 +107,10 @@ func NewMMap(mmapDir string) *MMap {
 		m.loadSize = m.initSize
 	}
 	m.allocate(m.initSize)
+	m.ext = openRegion(mmapDir, metaFileName)
+	if len(m.ext.data) < extSize {
+		m.ext.resize(extSize)
+	}
 	return m
 }
 
@@ -109,6 +130,7 @@ func (m *MMap) InitData(c *Cedar) {
 	c.blocks = m.block[:m.initSize>>8]
 	c.nInfos = m.nInfo[:m.initSize]
 	c.MetaInfo = m.metaInfo
+	c.metaExt = (*metaExt)(unsafe.Pointer(&m.ext.data[0]))
 	c.mmap = m
 	c.LoadSize = m.loadSize
 }
@@ -123,6 +145,19 @@ func (m *MMap) AddBlock(c *Cedar, capacity int) {
 	c.nInfos = m.nInfo[:c.capacity]
 }
 
+// Truncate shrinks the files to `capacity` nodes and remaps them into Cedar inplace
+func (m *MMap) Truncate(c *Cedar, capacity int) {
+	m.allocate(capacity)
+	_assert(m.arrayFile.Truncate(int64(m.arrayMSize)) == nil, "failed to truncate arrayFile")
+	_assert(m.blockFile.Truncate(int64(m.blockMSize)) == nil, "failed to truncate blockFile")
+	_assert(m.nInfoFile.Truncate(int64(m.nInfoMSize)) == nil, "failed to truncate nInfoFile")
+
+	c.MetaInfo = m.metaInfo
+	c.array = m.array[:c.capacity]
+	c.blocks = m.block[:c.capacity>>8]
+	c.nInfos = m.nInfo[:c.capacity]
+}
+
 // allocate remmap depends on arrayMSize blockMSize nInfoMSize
 func (m *MMap) allocate(cap int) {
 	// compute memory size
@@ -154,6 +189,12 @@ func (m *MMap) allocate(cap int) {
 }
 
 func (c *Cedar) Close() {
+	if c.tail != nil {
+		c.tail.close()
+	}
+	c.eachStore(func(p *payloadStore) {
+		p.close()
+	})
 	if c.useMMap {
 		munmap(c.mmap.arrayBytes)
 		munmap(c.mmap.blockBytes)
@@ -161,7 +202,78 @@ func (c *Cedar) Close() {
 		_assert(c.mmap.arrayFile.Close() == nil, "close file fail")
 		_assert(c.mmap.blockFile.Close() == nil, "close file fail")
 		_assert(c.mmap.nInfoFile.Close() == nil, "close file fail")
+		c.mmap.ext.close()
+	}
+}
+
+// region is a growable byte buffer, kept on the heap or mapped to a file
+// next to the other files of the trie.
+type region struct {
+	file *os.File
+	data []byte
+}
+
+// hasRegion reports whether the file `name` in `dir` holds any data.
+func hasRegion(dir, name string) bool {
+	info, err := os.Stat(path.Join(dir, name))
+	return err == nil && info.Size() > 0
+}
+
+// openRegion maps the file `name` in `dir`, it is created if not exist.
+func openRegion(dir, name string) *region {
+	file, err := os.OpenFile(path.Join(dir, name), os.O_CREATE|os.O_RDWR, fileMode)
+	_assert(err == nil, "Open %s fail %v", name, err)
+
+	r := &region{file: file}
+	if info, _ := file.Stat(); info.Size() > 0 {
+		r.data = mmap(file, int(info.Size()))
+	}
+	return r
+}
+
+// reserve grows the region so that it holds at least `size` bytes.
+func (r *region) reserve(size int) {
+	if size <= len(r.data) {
+		return
+	}
+
+	n := 2 * len(r.data)
+	if n < size {
+		n = size
+	}
+	if n < pageSize {
+		n = pageSize
+	}
+	r.resize(n)
+}
+
+// resize reallocates the region to exactly `size` bytes, truncating the file.
+func (r *region) resize(size int) {
+	if r.file == nil {
+		data := make([]byte, size)
+		copy(data, r.data)
+		r.data = data
+		return
+	}
+
+	if len(r.data) > 0 {
+		munmap(r.data)
+		r.data = nil
+	}
+	_assert(r.file.Truncate(int64(size)) == nil, "failed to truncate %s", r.file.Name())
+	if size > 0 {
+		r.data = mmap(r.file, size)
+	}
+}
+
+func (r *region) close() {
+	if r.file == nil {
+		return
+	}
+	if len(r.data) > 0 {
+		munmap(r.data)
 	}
+	_assert(r.file.Close() == nil, "close file fail")
 }
 
 func _assert(condition bool, msg string, v ...interface{}) {
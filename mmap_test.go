@@ -153,3 +153,30 @@ func TestMLoadFromMMap(t *testing.T) {
 	}
 	log.Printf("gocedar Search cost %v, maxSingleCost %v", time.Since(t1), maxCost)
 }
+
+// TestMLoadOldFormat loads the files written before the file `meta`, the
+// keys are counted again when they are opened.
+func TestMLoadOldFormat(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{arrayFileName, blockFileName, nInfoFileName} {
+		data, err := os.ReadFile(path.Join("testdata", "v0", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path.Join(dir, name), data, 0644))
+	}
+
+	cd := New(&Options{Reduced: true, UseMMap: true, MMapPath: dir})
+	require.Equal(t, 299, cd.Len())
+	v, err := cd.Get([]byte("key-100"))
+	require.NoError(t, err)
+	require.Equal(t, 100, v)
+	_, err = cd.Get([]byte("key-7"))
+	require.Error(t, err)
+	require.NoError(t, cd.Verify())
+	require.NoError(t, cd.Insert([]byte("key-7"), 7))
+	cd.Close()
+
+	cd = New(&Options{Reduced: true, UseMMap: true, MMapPath: dir})
+	defer cd.Close()
+	require.Equal(t, 300, cd.Len())
+	require.NoError(t, cd.Verify())
+}
@@ -0,0 +1,164 @@
+package gocedar
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalizer maps a key to the form it is stored and looked up in, see
+// Options.Normalizer. A NFKC normalizer is `norm.NFKC.Bytes` of the package
+// golang.org/x/text/unicode/norm.
+type Normalizer func(key []byte) []byte
+
+// Chain returns a Normalizer applying the normalizers in order.
+func Chain(fns ...Normalizer) Normalizer {
+	return func(key []byte) []byte {
+		for _, fn := range fns {
+			key = fn(key)
+		}
+		return key
+	}
+}
+
+// MapRunes returns a Normalizer replacing the characters in `m` by their
+// mappings, e.g. the traditional Chinese characters by the simplified ones.
+func MapRunes(m map[rune]string) Normalizer {
+	return func(key []byte) []byte {
+		return mapRunes(key, func(r rune) (string, bool) {
+			s, ok := m[r]
+			return s, ok
+		})
+	}
+}
+
+// FoldCase maps the letters to their lower case.
+func FoldCase(key []byte) []byte {
+	return mapRunes(key, func(r rune) (string, bool) {
+		if l := unicode.ToLower(r); l != r {
+			return string(l), true
+		}
+		return "", false
+	})
+}
+
+// FoldWidth maps the full-width forms of the ASCII characters and the
+// ideographic space to the ASCII ones.
+func FoldWidth(key []byte) []byte {
+	return mapRunes(key, func(r rune) (string, bool) {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			return string(r - 0xFF01 + '!'), true
+		case r == 0x3000:
+			return " ", true
+		}
+		return "", false
+	})
+}
+
+// punct are the punctuation marks folded by FoldPunct.
+var punct = map[rune]string{
+	'‘': "'", '’': "'", '‛': "'", '′': "'",
+	'“': `"`, '”': `"`, '„': `"`, '″': `"`,
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-",
+	'…': "...", '、': ",", '。': ".",
+}
+
+// FoldPunct maps the typographic quotes, dashes and the CJK punctuation marks
+// to the ASCII ones.
+func FoldPunct(key []byte) []byte {
+	return mapRunes(key, func(r rune) (string, bool) {
+		s, ok := punct[r]
+		return s, ok
+	})
+}
+
+// mapRunes replaces the characters fn maps, it returns `key` itself if none.
+func mapRunes(key []byte, fn func(r rune) (string, bool)) []byte {
+	var buf []byte
+	for i := 0; i < len(key); {
+		r, size := utf8.DecodeRune(key[i:])
+		if s, ok := fn(r); ok {
+			if buf == nil {
+				buf = append(make([]byte, 0, len(key)), key[:i]...)
+			}
+			buf = append(buf, s...)
+		} else if buf != nil {
+			buf = append(buf, key[i:i+size]...)
+		}
+		i += size
+	}
+
+	if buf == nil {
+		return key
+	}
+	return buf
+}
+
+// originKeys returns the store of the keys changed by the Normalizer,
+// creating it on the first use.
+func (cd *Cedar) originKeys() *payloadStore {
+	if cd.origins == nil {
+		cd.origins = cd.openStore(originFileName)
+	}
+	return cd.origins
+}
+
+// keepOrigin records `key` for the node `to` holding its value if the
+// Normalizer changed it, and drops the one recorded before otherwise.
+func (cd *Cedar) keepOrigin(to int, key []byte) {
+	if cd.normalize == nil {
+		return
+	}
+
+	if bytes.Equal(cd.normalize(key), key) {
+		if cd.origins != nil {
+			cd.origins.free(cd.origins.ref(to))
+			cd.origins.setRef(to, 0)
+		}
+		return
+	}
+
+	p := cd.originKeys()
+	p.setRef(to, p.put(p.ref(to), key))
+}
+
+// original returns the key given for the stored key `key`.
+func (cd *Cedar) original(key []byte) []byte {
+	if cd.origins != nil {
+		if to, err := cd.valueNode(key); err == nil && cd.origins.ref(to) != 0 {
+			return append([]byte(nil), cd.origins.get(cd.origins.ref(to))...)
+		}
+	}
+	return cd.unescape(key)
+}
+
+// Key returns the key ending at the node `id`, as it was given before the
+// Normalizer changed it. `id` is a node returned by PrefixMatch, PrefixPredict
+// or Jump.
+func (cd *Cedar) Key(id int) ([]byte, error) {
+	to, err := cd.holder(id)
+	if err != nil {
+		return nil, err
+	}
+	if cd.origins != nil && cd.origins.ref(to) != 0 {
+		return append([]byte(nil), cd.origins.get(cd.origins.ref(to))...), nil
+	}
+
+	// climb up to the root, collecting the labels backwards.
+	var key []byte
+	for n := to; n > 0; n = cd.array[n].check {
+		if label := byte(n ^ cd.array[cd.array[n].check].base(cd.Reduced)); label != 0 {
+			key = append(key, label)
+		}
+	}
+	for i, j := 0, len(key)-1; i < j; i, j = i+1, j-1 {
+		key[i], key[j] = key[j], key[i]
+	}
+
+	if v := cd.array[to].baseV; cd.tail != nil && v >= 0 && v != ValLimit {
+		suffix, _ := cd.tail.entry(v)
+		key = append(key, suffix...)
+	}
+	return cd.unescape(key), nil
+}
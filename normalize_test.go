@@ -0,0 +1,49 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestNormalizer(t *testing.T) {
+	norm := Chain(FoldWidth, FoldCase, FoldPunct, MapRunes(map[rune]string{'夢': "梦"}))
+	tt.Equal(t, "cryin'", string(norm([]byte("ＣＲＹＩＮ’"))))
+
+	cd := New(&Options{Reduced: true, Counts: true, Normalizer: norm})
+	tt.Nil(t, cd.Insert([]byte("Cryin'"), 1))
+	tt.Nil(t, cd.Insert([]byte("夢想"), 2))
+	tt.Nil(t, cd.Insert([]byte("abc"), 3))
+
+	val, err := cd.Get([]byte("CRYIN’"))
+	tt.Nil(t, err)
+	tt.Equal(t, 1, val)
+	val, err = cd.Get([]byte("梦想"))
+	tt.Nil(t, err)
+	tt.Equal(t, 2, val)
+	tt.Equal(t, 1, len(cd.PrefixPredict([]byte("ＣＲＹ"))))
+	tt.Equal(t, 1, len(cd.PrefixMatch([]byte("夢想家"))))
+
+	// the original keys are kept.
+	ids := cd.PrefixPredict([]byte("cry"))
+	key, err := cd.Key(ids[0])
+	tt.Nil(t, err)
+	tt.Equal(t, "Cryin'", string(key))
+	key, err = cd.Select(0)
+	tt.Nil(t, err)
+	tt.Equal(t, "abc", string(key))
+	key, err = cd.Select(1)
+	tt.Nil(t, err)
+	tt.Equal(t, "Cryin'", string(key))
+
+	_, err = cd.Compact()
+	tt.Nil(t, err)
+	ids = cd.PrefixPredict([]byte("梦"))
+	key, err = cd.Key(ids[0])
+	tt.Nil(t, err)
+	tt.Equal(t, "夢想", string(key))
+
+	tt.Nil(t, cd.Delete([]byte("CRYIN'")))
+	_, err = cd.Get([]byte("cryin'"))
+	tt.NotNil(t, err)
+}
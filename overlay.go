@@ -0,0 +1,195 @@
+package gocedar
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Overlay composes tries into one, as a small user dictionary over a large
+// system one: a key is looked up from the top layer down, and the first layer
+// holding it gives its value. Insert and Delete change the top layer only, a
+// key deleted while a lower layer holds it is hidden by a tombstone. The
+// tombstones live on the heap, they are not kept in the tries.
+type Overlay struct {
+	layers []*Cedar
+	// tombs holds the keys hidden by every layer from the layers below it.
+	tombs []*Cedar
+}
+
+// Hit is a key found in an Overlay, with its value and the layer holding it.
+type Hit struct {
+	Key   []byte
+	Value int
+	Layer int
+}
+
+// NewOverlay returns an Overlay of the layers, from the top one down.
+func NewOverlay(layers ...*Cedar) *Overlay {
+	o := &Overlay{layers: layers, tombs: make([]*Cedar, len(layers))}
+	for i, cd := range layers {
+		o.tombs[i] = New(&Options{Reduced: true, Binary: true, Normalizer: cd.normalize})
+	}
+	return o
+}
+
+// Get returns the value of the key in the top layer holding it, ErrNoKey if
+// none does or a tombstone hides it.
+func (o *Overlay) Get(key []byte) (int, error) {
+	val, _, err := o.get(key)
+	return val, err
+}
+
+// get returns the value of the key and the layer holding it.
+func (o *Overlay) get(key []byte) (int, int, error) {
+	for i, cd := range o.layers {
+		if val, err := cd.Get(key); err == nil {
+			return val, i, nil
+		}
+		if _, err := o.tombs[i].Get(key); err == nil {
+			break
+		}
+	}
+	return 0, -1, ErrNoKey
+}
+
+// Insert the key for the value to the top layer, the key is no longer hidden.
+func (o *Overlay) Insert(key []byte, val int) error {
+	if err := o.layers[0].Insert(key, val); err != nil {
+		return err
+	}
+	if _, err := o.tombs[0].Get(key); err == nil {
+		return o.tombs[0].Delete(key)
+	}
+	return nil
+}
+
+// Delete the key from the top layer, and hide it from the layers below. It
+// returns ErrNoKey if the key is not found by Get.
+func (o *Overlay) Delete(key []byte) error {
+	_, layer, err := o.get(key)
+	if err != nil {
+		return err
+	}
+	if layer == 0 {
+		if err = o.layers[0].Delete(key); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err = o.get(key); err == nil {
+		return o.tombs[0].Insert(key, 0)
+	}
+	return nil
+}
+
+// PrefixPredict returns the keys found by Get having `prefix` as their
+// prefix, in the order of the keys, at most `n[0]` of them if given. The keys
+// are as stored, see Walk.
+func (o *Overlay) PrefixPredict(prefix []byte, n ...int) []Hit {
+	var hits []Hit
+	_ = o.walk(prefix, func(key []byte, val, layer int) bool {
+		hits = append(hits, Hit{Key: key, Value: val, Layer: layer})
+		return len(n) == 0 || len(hits) < n[0]
+	})
+	return hits
+}
+
+// PrefixMatch returns the keys found by Get which are prefixes of `key`, the
+// shorter ones first, at most `n[0]` of them if given. The keys are as stored,
+// see Walk.
+func (o *Overlay) PrefixMatch(key []byte, n ...int) []Hit {
+	var hits []Hit
+	seen := make(map[string]bool)
+	for _, cd := range o.layers {
+		escaped := cd.escape(key)
+		cd.prefixMatch(escaped, func(_, end int) bool {
+			key := cd.unescape(escaped[:end])
+			if seen[string(key)] {
+				return true
+			}
+			seen[string(key)] = true
+
+			if val, layer, err := o.get(key); err == nil {
+				hits = append(hits, Hit{Key: append([]byte(nil), key...), Value: val, Layer: layer})
+			}
+			return true
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return len(hits[i].Key) < len(hits[j].Key) })
+	if len(n) > 0 && n[0] < len(hits) {
+		hits = hits[:n[0]]
+	}
+	return hits
+}
+
+// Walk calls fn with every key found by Get and its value in the order of the
+// keys, until fn returns false. The layers are walked side by side, the key is
+// as stored, changed by the Normalizer if any. It returns ErrStale if a layer
+// changes during the walk.
+func (o *Overlay) Walk(fn func(key []byte, val int) bool) error {
+	return o.walk(nil, func(key []byte, val, _ int) bool {
+		return fn(key, val)
+	})
+}
+
+// walk calls fn with every key found by Get having `prefix` as its prefix,
+// with its value and the layer holding it, in the order of the keys.
+func (o *Overlay) walk(prefix []byte, fn func(key []byte, val, layer int) bool) error {
+	iters := make([]*keyIter, len(o.layers))
+	for i, cd := range o.layers {
+		key := cd.escape(prefix)
+		from, rest, err := cd.jump(key, 0)
+		if err != nil {
+			iters[i] = &keyIter{cd: cd, gen: cd.gen}
+			continue
+		}
+
+		// the prefix may end in the tail of a leaf, past the key leading to it.
+		depth := len(key)
+		if v := cd.array[from].baseV; cd.tail != nil && from != 0 && v >= 0 {
+			suffix, _ := cd.tail.entry(v)
+			depth -= len(suffix) - len(rest)
+		}
+		iters[i] = newKeyIter(cd, from, key[:depth])
+		iters[i].prefix = key
+		iters[i].next()
+	}
+
+	for {
+		// the smallest key any layer is at.
+		var min []byte
+		for _, it := range iters {
+			if err := it.check(); err != nil {
+				return err
+			}
+			if key := it.cd.unescape(it.key); it.ok && (min == nil || bytes.Compare(key, min) < 0) {
+				min = append(min[:0], key...)
+			}
+		}
+		if min == nil {
+			return nil
+		}
+
+		// the top layer at the key gives the value, unless a tombstone above hides it.
+		layer, val := -1, 0
+		for i, it := range iters {
+			if it.ok && bytes.Equal(it.cd.unescape(it.key), min) {
+				layer, val = i, it.val
+				break
+			}
+			if _, err := o.tombs[i].Get(min); err == nil {
+				break
+			}
+		}
+		if layer >= 0 && !fn(min, val, layer) {
+			return nil
+		}
+
+		for _, it := range iters {
+			if it.ok && bytes.Equal(it.cd.unescape(it.key), min) {
+				it.next()
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestOverlay(t *testing.T) {
+	system := New(&Options{Tail: true})
+	for key, val := range map[string]int{"北京": 1, "北京大学": 2, "南京": 3, "南京市": 4} {
+		tt.Nil(t, system.Insert([]byte(key), val))
+	}
+	user := New(&Options{Reduced: true})
+	tt.Nil(t, user.Insert([]byte("北京大学"), 20))
+	tt.Nil(t, user.Insert([]byte("北京大"), 10))
+
+	o := NewOverlay(user, system)
+	val, err := o.Get([]byte("北京大学"))
+	tt.Nil(t, err)
+	tt.Equal(t, 20, val)
+	val, err = o.Get([]byte("南京"))
+	tt.Nil(t, err)
+	tt.Equal(t, 3, val)
+
+	// the key of the system trie is hidden, not deleted from it.
+	tt.Nil(t, o.Delete([]byte("南京市")))
+	_, err = o.Get([]byte("南京市"))
+	tt.Equal(t, ErrNoKey, err)
+	tt.Equal(t, ErrNoKey, o.Delete([]byte("南京市")))
+	_, err = system.Get([]byte("南京市"))
+	tt.Nil(t, err)
+
+	// deleting the user key shows the system one again.
+	tt.Nil(t, o.Delete([]byte("北京大学")))
+	_, err = o.Get([]byte("北京大学"))
+	tt.Equal(t, ErrNoKey, err)
+	tt.Nil(t, o.Insert([]byte("北京大学"), 30))
+
+	hits := o.PrefixMatch([]byte("北京大学生"))
+	tt.Equal(t, []Hit{
+		{Key: []byte("北京"), Value: 1, Layer: 1},
+		{Key: []byte("北京大"), Value: 10, Layer: 0},
+		{Key: []byte("北京大学"), Value: 30, Layer: 0},
+	}, hits)
+	tt.Equal(t, 1, len(o.PrefixMatch([]byte("北京大学生"), 1)))
+
+	hits = o.PrefixPredict([]byte("南"))
+	tt.Equal(t, []Hit{{Key: []byte("南京"), Value: 3, Layer: 1}}, hits)
+
+	var keys []string
+	var vals []int
+	tt.Nil(t, o.Walk(func(key []byte, val int) bool {
+		keys = append(keys, string(key))
+		vals = append(vals, val)
+		return true
+	}))
+	tt.Equal(t, []string{"北京", "北京大", "北京大学", "南京"}, keys)
+	tt.Equal(t, []int{1, 10, 30, 3}, vals)
+
+	tt.Equal(t, ErrStale, o.Walk(func(key []byte, _ int) bool {
+		_ = user.Insert([]byte("西安"), 5)
+		return true
+	}))
+
+	// the three give the keys as stored, changed by the Normalizer.
+	folded := New(&Options{Reduced: true, Normalizer: FoldCase})
+	tt.Nil(t, folded.Insert([]byte("Go"), 1))
+	tt.Nil(t, folded.Insert([]byte("GoLang"), 2))
+	o = NewOverlay(folded)
+	tt.Equal(t, []Hit{
+		{Key: []byte("go"), Value: 1},
+		{Key: []byte("golang"), Value: 2},
+	}, o.PrefixMatch([]byte("GOLANG!")))
+	tt.Equal(t, []Hit{
+		{Key: []byte("go"), Value: 1},
+		{Key: []byte("golang"), Value: 2},
+	}, o.PrefixPredict([]byte("GO")))
+	keys = keys[:0]
+	tt.Nil(t, o.Walk(func(key []byte, _ int) bool {
+		keys = append(keys, string(key))
+		return true
+	}))
+	tt.Equal(t, []string{"go", "golang"}, keys)
+}
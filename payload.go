@@ -0,0 +1,231 @@
+package gocedar
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	payloadClasses     = 40                   // the size classes of the records, 16 << class bytes
+	payloadHeader      = 8 + 8*payloadClasses // the used bytes and the head of the free list of every class
+	payloadEntryHeader = 16                   // the size class (8 bytes) and the length of the payload (8 bytes)
+	payloadMinSize     = 16                   // the capacity of the smallest class
+	refSize            = 8                    // the record offset of a node
+	payloadFileName    = "payload"            // the records of the payloads
+	postingFileName    = "postings"           // the records of the posting lists
+	originFileName     = "origin"             // the records of the keys changed by the Normalizer
+	refFileSuffix      = ".ref"               // the record offset of every node
+)
+
+// ErrNoPayload the key has no payload
+var ErrNoPayload = errors.New("cedar: not have payload")
+
+// payloadStore keeps a blob for a key next to its value, for the payloads and
+// the posting lists. The records live in an append-only region, and every node
+// has the offset of the record of the key it holds in a second region parallel
+// to `array`, which moves with the value.
+//
+// A record is sized to a power of two, and the record freed by `Delete` or
+// outgrown by a write is put to the free list of its size class for reuse.
+type payloadStore struct {
+	blobs *region
+	refs  *region
+}
+
+func newPayloadStore(blobs, refs *region, capacity int) *payloadStore {
+	p := &payloadStore{blobs: blobs, refs: refs}
+	if len(blobs.data) == 0 {
+		blobs.resize(payloadHeader)
+		p.setUsed(payloadHeader)
+	}
+	p.fit(capacity)
+	return p
+}
+
+// payloads returns the payload store, creating it on the first use.
+func (cd *Cedar) payloads() *payloadStore {
+	if cd.payload == nil {
+		cd.payload = cd.openStore(payloadFileName)
+	}
+	return cd.payload
+}
+
+// openStore returns a store kept in the files `name` and `name.ref` for mmap.
+func (cd *Cedar) openStore(name string) *payloadStore {
+	if !cd.useMMap {
+		return newPayloadStore(&region{}, &region{}, cd.capacity)
+	}
+
+	dir := cd.mmap.mmapDir
+	return newPayloadStore(openRegion(dir, name), openRegion(dir, name+refFileSuffix), cd.capacity)
+}
+
+// stores returns the stores keeping records for the nodes, nil if not used.
+func (cd *Cedar) stores() [3]*payloadStore {
+	return [3]*payloadStore{cd.payload, cd.postings, cd.origins}
+}
+
+// eachStore calls fn with every store in use.
+func (cd *Cedar) eachStore(fn func(p *payloadStore)) {
+	for _, p := range cd.stores() {
+		if p != nil {
+			fn(p)
+		}
+	}
+}
+
+// fit resizes the offsets to `capacity` nodes.
+func (p *payloadStore) fit(capacity int) {
+	if len(p.refs.data) != capacity*refSize {
+		p.refs.resize(capacity * refSize)
+	}
+}
+
+func (p *payloadStore) ref(node int) int {
+	return int(binary.LittleEndian.Uint64(p.refs.data[node*refSize:]))
+}
+
+func (p *payloadStore) setRef(node, off int) {
+	binary.LittleEndian.PutUint64(p.refs.data[node*refSize:], uint64(off))
+}
+
+// move gives the record of the node `from` to the node `to`.
+func (p *payloadStore) move(from, to int) {
+	p.setRef(to, p.ref(from))
+	p.setRef(from, 0)
+}
+
+func (p *payloadStore) used() int {
+	return int(binary.LittleEndian.Uint64(p.blobs.data))
+}
+
+func (p *payloadStore) setUsed(n int) {
+	binary.LittleEndian.PutUint64(p.blobs.data, uint64(n))
+}
+
+// head returns the offset of the first free record of the size class, 0 if none.
+func (p *payloadStore) head(class int) int {
+	return int(binary.LittleEndian.Uint64(p.blobs.data[8+8*class:]))
+}
+
+func (p *payloadStore) setHead(class, off int) {
+	binary.LittleEndian.PutUint64(p.blobs.data[8+8*class:], uint64(off))
+}
+
+// class returns the smallest size class holding `n` bytes.
+func class(n int) int {
+	c := 0
+	for payloadMinSize<<c < n {
+		c++
+	}
+	return c
+}
+
+// get returns the payload of the record at `off`, it is valid until the next write.
+func (p *payloadStore) get(off int) []byte {
+	n := int(binary.LittleEndian.Uint64(p.blobs.data[off+8:]))
+	return p.blobs.data[off+payloadEntryHeader : off+payloadEntryHeader+n]
+}
+
+// put writes `data` to the record at `off`, it returns the offset of the new
+// record if `data` does not fit, and frees the old one. `off` 0 means no record.
+func (p *payloadStore) put(off int, data []byte) int {
+	if off == 0 || payloadMinSize<<p.class(off) < len(data) {
+		p.free(off)
+		off = p.alloc(class(len(data)))
+	}
+
+	binary.LittleEndian.PutUint64(p.blobs.data[off+8:], uint64(len(data)))
+	copy(p.blobs.data[off+payloadEntryHeader:], data)
+	return off
+}
+
+func (p *payloadStore) class(off int) int {
+	return int(binary.LittleEndian.Uint64(p.blobs.data[off:]))
+}
+
+// alloc pops a record of the size class from its free list, or appends one.
+func (p *payloadStore) alloc(class int) int {
+	if off := p.head(class); off != 0 {
+		// the free record keeps the next one in place of the length.
+		p.setHead(class, int(binary.LittleEndian.Uint64(p.blobs.data[off+8:])))
+		return off
+	}
+
+	off := p.used()
+	p.blobs.reserve(off + payloadEntryHeader + payloadMinSize<<class)
+	binary.LittleEndian.PutUint64(p.blobs.data[off:], uint64(class))
+	p.setUsed(off + payloadEntryHeader + payloadMinSize<<class)
+	return off
+}
+
+// free pushes the record at `off` to the free list of its size class.
+func (p *payloadStore) free(off int) {
+	if off == 0 {
+		return
+	}
+
+	c := p.class(off)
+	binary.LittleEndian.PutUint64(p.blobs.data[off+8:], uint64(p.head(c)))
+	p.setHead(c, off)
+}
+
+// reset drops all of the records.
+func (p *payloadStore) reset(capacity int) {
+	p.blobs.resize(payloadHeader)
+	p.setUsed(payloadHeader)
+	for c := 0; c < payloadClasses; c++ {
+		p.setHead(c, 0)
+	}
+	p.refs.resize(0)
+	p.fit(capacity)
+}
+
+func (p *payloadStore) close() {
+	p.blobs.close()
+	p.refs.close()
+}
+
+// PutPayload attaches `data` to the key, replacing the payload it had. The
+// key must be in the trie, a nil or empty `data` removes the payload.
+func (cd *Cedar) PutPayload(key []byte, data []byte) error {
+	to, err := cd.valueNode(cd.escape(key))
+	if err != nil {
+		return err
+	}
+
+	p := cd.payloads()
+	if len(data) == 0 {
+		p.free(p.ref(to))
+		p.setRef(to, 0)
+		return nil
+	}
+
+	p.setRef(to, p.put(p.ref(to), data))
+	return nil
+}
+
+// GetPayload returns a copy of the payload of the key.
+func (cd *Cedar) GetPayload(key []byte) ([]byte, error) {
+	to, err := cd.valueNode(cd.escape(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if cd.payload == nil || cd.payload.ref(to) == 0 {
+		return nil, ErrNoPayload
+	}
+	return append([]byte(nil), cd.payload.get(cd.payload.ref(to))...), nil
+}
+
+// moveValue is called when the value of the key held by a leaf goes down to
+// its new child `to`. The leaf is taken from `check`, as `follow` may have
+// relocated it.
+func (cd *Cedar) moveValue(to int) {
+	if cd.counts != nil {
+		cd.counts[to] = 1
+	}
+	cd.eachStore(func(p *payloadStore) {
+		p.move(cd.array[to].check, to)
+	})
+}
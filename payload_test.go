@@ -0,0 +1,69 @@
+package gocedar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestPayload(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	_, err := cd.GetPayload([]byte("a"))
+	tt.Equal(t, ErrNoKey, err)
+	tt.Equal(t, ErrNoKey, cd.PutPayload([]byte("a"), []byte("x")))
+
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("song-%d", i))
+		tt.Nil(t, cd.Insert(key, i))
+		tt.Nil(t, cd.PutPayload(key, []byte(fmt.Sprintf("lyrics of %d", i))))
+	}
+	for i := 0; i < 1000; i++ {
+		data, err := cd.GetPayload([]byte(fmt.Sprintf("song-%d", i)))
+		tt.Nil(t, err)
+		tt.Equal(t, fmt.Sprintf("lyrics of %d", i), string(data))
+	}
+
+	// the records freed by Delete are reused.
+	used := cd.payload.used()
+	for i := 0; i < 100; i++ {
+		tt.Nil(t, cd.Delete([]byte(fmt.Sprintf("song-%d", i))))
+	}
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("album-%d", i))
+		tt.Nil(t, cd.Insert(key, i))
+		tt.Nil(t, cd.PutPayload(key, []byte("cover")))
+	}
+	tt.Equal(t, used, cd.payload.used())
+
+	tt.Nil(t, cd.PutPayload([]byte("song-100"), nil))
+	_, err = cd.GetPayload([]byte("song-100"))
+	tt.Equal(t, ErrNoPayload, err)
+
+	_, err = cd.Compact()
+	tt.Nil(t, err)
+	data, err := cd.GetPayload([]byte("song-999"))
+	tt.Nil(t, err)
+	tt.Equal(t, "lyrics of 999", string(data))
+	data, err = cd.GetPayload([]byte("album-0"))
+	tt.Nil(t, err)
+	tt.Equal(t, "cover", string(data))
+}
+
+func TestPayloadMMap(t *testing.T) {
+	dir := t.TempDir()
+	cd := New(&Options{UseMMap: true, MMapPath: dir, Reduced: true})
+	for _, word := range words {
+		tt.Nil(t, cd.Insert([]byte(word), 1))
+		tt.Nil(t, cd.PutPayload([]byte(word), []byte(word+word)))
+	}
+	cd.Close()
+
+	cd = New(&Options{UseMMap: true, MMapPath: dir, Reduced: true})
+	defer cd.Close()
+	for _, word := range words {
+		data, err := cd.GetPayload([]byte(word))
+		tt.Nil(t, err)
+		tt.Equal(t, word+word, string(data))
+	}
+}
@@ -0,0 +1,131 @@
+package gocedar
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// postingLists returns the posting store, creating it on the first use. A
+// posting list is the sorted ids of a key, 8 bytes each.
+func (cd *Cedar) postingLists() *payloadStore {
+	if cd.postings == nil {
+		cd.postings = cd.openStore(postingFileName)
+	}
+	return cd.postings
+}
+
+// postingsOf returns the ids added to the key held by the node `to`.
+func (cd *Cedar) postingsOf(to int) []int {
+	if cd.postings == nil || cd.postings.ref(to) == 0 {
+		return nil
+	}
+
+	data := cd.postings.get(cd.postings.ref(to))
+	ids := make([]int, len(data)/8)
+	for i := range ids {
+		ids[i] = int(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+	return ids
+}
+
+// setPostings writes the ids of the key held by the node `to`.
+func (cd *Cedar) setPostings(to int, ids []int) {
+	data := make([]byte, len(ids)*8)
+	for i, id := range ids {
+		binary.LittleEndian.PutUint64(data[i*8:], uint64(id))
+	}
+
+	p := cd.postingLists()
+	p.setRef(to, p.put(p.ref(to), data))
+}
+
+// Add adds `id` to the posting list of the key, the key is inserted if it is
+// not there. The value of the key is the number of ids in its list, so a key
+// used with Add and Remove should not be used with Insert and Update.
+func (cd *Cedar) Add(key []byte, id int) error {
+	origin := key
+	key = cd.escape(key)
+
+	var ids []int
+	if to, err := cd.valueNode(key); err == nil {
+		ids = cd.postingsOf(to)
+	}
+
+	i := sort.SearchInts(ids, id)
+	if i < len(ids) && ids[i] == id {
+		return nil
+	}
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+
+	to := cd.insert(key, len(ids))
+	cd.setPostings(to, ids)
+	cd.keepOrigin(to, origin)
+	return nil
+}
+
+// Remove removes `id` from the posting list of the key, and the key itself
+// with the last id.
+func (cd *Cedar) Remove(key []byte, id int) error {
+	key = cd.escape(key)
+	to, err := cd.valueNode(key)
+	if err != nil {
+		return err
+	}
+
+	ids := cd.postingsOf(to)
+	i := sort.SearchInts(ids, id)
+	if i == len(ids) || ids[i] != id {
+		return ErrNoVal
+	}
+	ids = append(ids[:i], ids[i+1:]...)
+
+	if len(ids) == 0 {
+		cd.drop(to)
+		return nil
+	}
+	cd.setPostings(cd.insert(key, len(ids)), ids)
+	return nil
+}
+
+// Values returns the sorted ids added to the key.
+func (cd *Cedar) Values(key []byte) ([]int, error) {
+	to, err := cd.valueNode(cd.escape(key))
+	if err != nil {
+		return nil, err
+	}
+	return cd.postingsOf(to), nil
+}
+
+// PrefixMatchValues returns the sorted union of the ids added to the keys
+// which are a prefix of `key`.
+func (cd *Cedar) PrefixMatchValues(key []byte) []int {
+	return cd.union(cd.PrefixMatch(key))
+}
+
+// PrefixPredictValues returns the sorted union of the ids added to the keys
+// having `prefix` as their prefix.
+func (cd *Cedar) PrefixPredictValues(prefix []byte) []int {
+	return cd.union(cd.PrefixPredict(prefix))
+}
+
+// union merges the posting lists of the keys ending at the nodes.
+func (cd *Cedar) union(nodes []int) []int {
+	var ids []int
+	for _, n := range nodes {
+		if to, err := cd.holder(n); err == nil {
+			ids = append(ids, cd.postingsOf(to)...)
+		}
+	}
+
+	sort.Ints(ids)
+	j := 0
+	for i, id := range ids {
+		if i == 0 || id != ids[j-1] {
+			ids[j] = id
+			j++
+		}
+	}
+	return ids[:j]
+}
@@ -0,0 +1,56 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestPostings(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	tt.Nil(t, cd.Add([]byte("hello"), 7))
+	tt.Nil(t, cd.Add([]byte("hello"), 3))
+	tt.Nil(t, cd.Add([]byte("hello"), 7))
+	tt.Nil(t, cd.Add([]byte("hello world"), 5))
+	tt.Nil(t, cd.Add([]byte("help"), 3))
+
+	ids, err := cd.Values([]byte("hello"))
+	tt.Nil(t, err)
+	tt.Equal(t, []int{3, 7}, ids)
+	val, err := cd.Get([]byte("hello"))
+	tt.Nil(t, err)
+	tt.Equal(t, 2, val)
+
+	tt.Equal(t, []int{3, 5, 7}, cd.PrefixPredictValues([]byte("hel")))
+	tt.Equal(t, []int{3, 5, 7}, cd.PrefixMatchValues([]byte("hello world!")))
+
+	tt.Equal(t, ErrNoVal, cd.Remove([]byte("hello"), 1))
+	tt.Nil(t, cd.Remove([]byte("hello"), 7))
+	ids, err = cd.Values([]byte("hello"))
+	tt.Nil(t, err)
+	tt.Equal(t, []int{3}, ids)
+
+	// the key goes with its last id.
+	tt.Nil(t, cd.Remove([]byte("help"), 3))
+	_, err = cd.Values([]byte("help"))
+	tt.Equal(t, ErrNoKey, err)
+	tt.Equal(t, 2, cd.Len())
+}
+
+func TestPostingsMMap(t *testing.T) {
+	dir := t.TempDir()
+	cd := New(&Options{UseMMap: true, MMapPath: dir, Reduced: true})
+	for i, word := range words {
+		tt.Nil(t, cd.Add([]byte(word), i))
+		tt.Nil(t, cd.Add([]byte(word), -i))
+	}
+	_, err := cd.Compact()
+	tt.Nil(t, err)
+	cd.Close()
+
+	cd = New(&Options{UseMMap: true, MMapPath: dir, Reduced: true})
+	defer cd.Close()
+	ids, err := cd.Values([]byte(words[1]))
+	tt.Nil(t, err)
+	tt.Equal(t, []int{-1, 1}, ids)
+}
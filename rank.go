@@ -0,0 +1,181 @@
+package gocedar
+
+import "bytes"
+
+// addCount adds `n` to the number of keys, and to the counts of the node `to`
+// and all the nodes above it.
+func (cd *Cedar) addCount(to, n int) {
+	cd.keys += n
+	if cd.counts == nil {
+		return
+	}
+
+	for ; to > 0; to = cd.array[to].check {
+		cd.counts[to] += n
+	}
+	cd.counts[0] += n
+}
+
+// migrate counts the keys of a trie loaded from the files written before
+// the file `meta`, and marks it with the current version.
+func (cd *Cedar) migrate() {
+	cd.keys = 0
+	_ = cd.walk(0, nil, func([]byte, int) error {
+		cd.keys++
+		return nil
+	})
+	cd.version = metaVersion
+}
+
+// recount rebuilds the counts of all the nodes from the keys.
+func (cd *Cedar) recount() {
+	for i := range cd.counts {
+		cd.counts[i] = 0
+	}
+	cd.countFrom(0)
+}
+
+func (cd *Cedar) countFrom(from int) int {
+	if v := cd.array[from].baseV; cd.Reduced && v >= 0 {
+		if v != ValLimit {
+			cd.counts[from] = 1
+		}
+		return cd.counts[from]
+	}
+
+	n := 0
+	cd.children(from, func(label byte, to int) bool {
+		if label != 0 {
+			n += cd.countFrom(to)
+		} else if cd.array[to].baseV != ValLimit {
+			cd.counts[to] = 1
+			n++
+		}
+		return true
+	})
+	cd.counts[from] = n
+
+	return n
+}
+
+// Len returns the number of keys in the trie.
+func (cd *Cedar) Len() int {
+	return cd.keys
+}
+
+// CountPrefix returns the number of keys having `prefix` as their prefix, in
+// O(len(prefix)) with Counts, or by visiting the keys otherwise.
+func (cd *Cedar) CountPrefix(prefix []byte) int {
+	to, rest, err := cd.jump(cd.escape(prefix), 0)
+	if err != nil {
+		return 0
+	}
+	if len(rest) > 0 {
+		return 1
+	}
+	if cd.counts != nil {
+		return cd.counts[to]
+	}
+
+	n := 0
+	_ = cd.walk(to, nil, func([]byte, int) error {
+		n++
+		return nil
+	})
+	return n
+}
+
+// Rank returns the rank of `key` among the sorted keys of the trie, which is a
+// dense id not depending on the order the keys were inserted in. It needs Counts.
+func (cd *Cedar) Rank(key []byte) (int, error) {
+	if cd.counts == nil {
+		return 0, ErrNoCounts
+	}
+	key = cd.escape(key)
+
+	rank, from := 0, 0
+	for pos := 0; ; pos++ {
+		if v := cd.array[from].baseV; cd.Reduced && v >= 0 {
+			if v == ValLimit {
+				return 0, ErrNoKey
+			}
+			if cd.tail != nil {
+				if suffix, _ := cd.tail.entry(v); !bytes.Equal(suffix, key[pos:]) {
+					return 0, ErrNoKey
+				}
+			} else if pos < len(key) {
+				return 0, ErrNoKey
+			}
+			return rank, nil
+		}
+
+		label := byte(0)
+		if pos < len(key) {
+			label = key[pos]
+		}
+
+		// skip the keys below the children before the label.
+		to := -1
+		cd.children(from, func(c byte, n int) bool {
+			if pos < len(key) && c == 0 {
+				rank += cd.counts[n]
+				return true
+			}
+			if c >= label {
+				if c == label {
+					to = n
+				}
+				return false
+			}
+			rank += cd.counts[n]
+			return true
+		})
+
+		if to < 0 {
+			return 0, ErrNoKey
+		}
+		if pos == len(key) {
+			return rank, nil
+		}
+		from = to
+	}
+}
+
+// Select returns the key with the rank `i` among the sorted keys of the trie,
+// it is the reverse of Rank. It needs Counts.
+func (cd *Cedar) Select(i int) ([]byte, error) {
+	if cd.counts == nil {
+		return nil, ErrNoCounts
+	}
+	if i < 0 || i >= cd.counts[0] {
+		return nil, ErrNoKey
+	}
+
+	var key []byte
+	for from := 0; ; {
+		if v := cd.array[from].baseV; cd.Reduced && v >= 0 {
+			if cd.tail != nil {
+				suffix, _ := cd.tail.entry(v)
+				key = append(key, suffix...)
+			}
+			return cd.original(key), nil
+		}
+
+		// look for the child whose keys cover the rank.
+		to, label := -1, byte(0)
+		cd.children(from, func(c byte, n int) bool {
+			if i < cd.counts[n] {
+				to, label = n, c
+				return false
+			}
+			i -= cd.counts[n]
+			return true
+		})
+
+		if label == 0 {
+			return cd.original(key), nil
+		}
+		key = append(key, label)
+		from = to
+	}
+}
@@ -0,0 +1,59 @@
+package gocedar
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestRankSelect(t *testing.T) {
+	for _, opt := range []*Options{{Counts: true}, {Reduced: true, Counts: true}} {
+		cd := New(opt)
+		for i := len(words) - 1; i >= 0; i-- {
+			tt.Nil(t, cd.Insert([]byte(words[i]), i))
+		}
+		tt.Nil(t, cd.Delete([]byte(words[0])))
+
+		sorted := append([]string(nil), words[1:]...)
+		sort.Strings(sorted)
+		for i, word := range sorted {
+			rank, err := cd.Rank([]byte(word))
+			tt.Nil(t, err)
+			tt.Equal(t, i, rank)
+
+			key, err := cd.Select(i)
+			tt.Nil(t, err)
+			tt.Equal(t, word, string(key))
+		}
+
+		_, err := cd.Rank([]byte(words[0]))
+		tt.Equal(t, ErrNoKey, err)
+		_, err = cd.Select(len(sorted))
+		tt.Equal(t, ErrNoKey, err)
+	}
+
+	_, err := New(&Options{Reduced: true}).Rank([]byte("梦"))
+	tt.Equal(t, ErrNoCounts, err)
+}
+
+func TestCountPrefix(t *testing.T) {
+	for _, opt := range []*Options{{Reduced: true}, {Counts: true}, {Reduced: true, Counts: true}, {Tail: true, Counts: true}} {
+		cd := New(opt)
+		for i, word := range []string{"周杰伦", "周华健", "周深", "周", "陈奕迅"} {
+			tt.Nil(t, cd.Insert([]byte(word), i))
+		}
+		tt.Nil(t, cd.Update([]byte("周深"), 1))
+		tt.Equal(t, 5, cd.Len())
+
+		tt.Equal(t, 4, cd.CountPrefix([]byte("周")))
+		tt.Equal(t, 1, cd.CountPrefix([]byte("周深")))
+		tt.Equal(t, 1, cd.CountPrefix([]byte("陈")))
+		tt.Equal(t, 0, cd.CountPrefix([]byte("林")))
+
+		tt.Nil(t, cd.Delete([]byte("周")))
+		tt.NotNil(t, cd.Delete([]byte("周")))
+		tt.Equal(t, 4, cd.Len())
+		tt.Equal(t, 3, cd.CountPrefix([]byte("周")))
+	}
+}
@@ -0,0 +1,80 @@
+package gocedar
+
+import "unicode/utf8"
+
+// RuneMatch is a key found at the start of a text.
+type RuneMatch struct {
+	ID  int // the node holding the key, see Value
+	End int // the number of characters of the text the key covers
+}
+
+// GetString is the `Get` of a string key.
+func (cd *Cedar) GetString(key string) (int, error) {
+	return cd.Get([]byte(key))
+}
+
+// PrefixMatchRunes is the `PrefixMatch` stopping only on character boundaries,
+// the keys ending inside a character of `key` are left out, and `n` limits the
+// number of keys.
+func (cd *Cedar) PrefixMatchRunes(key string, n ...int) (ids []int) {
+	for _, m := range cd.CommonPrefixSearch(key, n...) {
+		ids = append(ids, m.ID)
+	}
+	return
+}
+
+// CommonPrefixSearch returns the keys which are a prefix of `text` ending on a
+// character boundary, the shortest first, with the offsets in characters of
+// their ends. With a Normalizer the offsets are in the characters of `text`,
+// which are normalized one by one, and the keys ending inside what a character
+// is mapped to are left out. `n` limits the number of keys.
+func (cd *Cedar) CommonPrefixSearch(text string, n ...int) (matches []RuneMatch) {
+	num := 0
+	if len(n) > 0 {
+		num = n[0]
+	}
+
+	var key []byte
+	// bounds holds the stored length of the text up to the end of every character.
+	var bounds []int
+	if cd.normalize == nil {
+		key = cd.escape([]byte(text))
+	} else {
+		for pos := 0; pos < len(text); {
+			_, w := utf8.DecodeRuneInString(text[pos:])
+			key = append(key, cd.escape([]byte(text[pos:pos+w]))...)
+			bounds = append(bounds, len(key))
+			pos += w
+		}
+	}
+
+	i := 0
+	cd.prefixMatch(key, func(to, end int) bool {
+		if end < len(key) && !utf8.RuneStart(key[end]) {
+			return true
+		}
+
+		runes := 0
+		if cd.normalize == nil {
+			runes = utf8.RuneCount(cd.unescape(key[:end]))
+		} else {
+			// the ends come in order, the characters mapped to nothing go with the one before.
+			for i < len(bounds) && bounds[i] < end {
+				i++
+			}
+			if i == len(bounds) || bounds[i] != end {
+				return true
+			}
+			for i+1 < len(bounds) && bounds[i+1] == end {
+				i++
+			}
+			runes = i + 1
+		}
+
+		matches = append(matches, RuneMatch{ID: to, End: runes})
+		num--
+		return num != 0
+	})
+
+	return
+}
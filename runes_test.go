@@ -0,0 +1,51 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestRunes(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	tt.Nil(t, cd.Insert([]byte("梦"), 1))
+	tt.Nil(t, cd.Insert([]byte("梦想"), 2))
+	// a key ending inside the character 想.
+	tt.Nil(t, cd.Insert([]byte("梦想")[:4], 3))
+
+	val, err := cd.GetString("梦想")
+	tt.Nil(t, err)
+	tt.Equal(t, 2, val)
+
+	tt.Equal(t, 3, len(cd.PrefixMatch([]byte("梦想家"))))
+	tt.Equal(t, 2, len(cd.PrefixMatchRunes("梦想家")))
+	tt.Equal(t, 1, len(cd.PrefixMatchRunes("梦想家", 1)))
+
+	matches := cd.CommonPrefixSearch("梦想家")
+	tt.Equal(t, 2, len(matches))
+	tt.Equal(t, 1, matches[0].End)
+	tt.Equal(t, 2, matches[1].End)
+	val, err = cd.Value(matches[1].ID)
+	tt.Nil(t, err)
+	tt.Equal(t, 2, val)
+
+	// the ends are in the characters of the text, not of the normalized one.
+	cd = New(&Options{Reduced: true, Normalizer: FoldPunct})
+	tt.Nil(t, cd.Insert([]byte("x…"), 1))
+	tt.Nil(t, cd.Insert([]byte("x."), 2))
+	tt.Nil(t, cd.Insert([]byte("x...y"), 3))
+	matches = cd.CommonPrefixSearch("x…yz")
+	tt.Equal(t, 2, len(matches))
+	tt.Equal(t, 2, matches[0].End)
+	tt.Equal(t, 3, matches[1].End)
+	val, err = cd.Value(matches[1].ID)
+	tt.Nil(t, err)
+	tt.Equal(t, 3, val)
+
+	// a character mapped to nothing is covered by the key before it.
+	cd = New(&Options{Reduced: true, Normalizer: MapRunes(map[rune]string{'·': ""})})
+	tt.Nil(t, cd.Insert([]byte("ab"), 1))
+	matches = cd.CommonPrefixSearch("a·b·c")
+	tt.Equal(t, 1, len(matches))
+	tt.Equal(t, 4, matches[0].End)
+}
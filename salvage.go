@@ -0,0 +1,320 @@
+package gocedar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"unsafe"
+)
+
+// ErrSalvageDir the salvaged trie would be written over the damaged one
+var ErrSalvageDir = errors.New("cedar: salvage into the damaged directory")
+
+// Lost is a subtree of a damaged trie which Salvage could not read.
+type Lost struct {
+	// Node is the node the subtree hangs from in the damaged trie.
+	Node int
+	// Prefix is the key leading to Node, or to the deepest node above it
+	// which could be read, nil if none.
+	Prefix []byte
+	// Reason tells what is broken.
+	Reason string
+}
+
+// salvager reads the files of a damaged trie, never trusting an offset it
+// has not checked against the size of the data.
+type salvager struct {
+	cd      *Cedar // the damaged trie, on the heap
+	refs    [3][]byte
+	blobs   [3][]byte
+	reached []bool
+	lost    []Lost
+}
+
+// Salvage reads the files of the mmap trie in `dir`, which may be damaged so
+// that New fails or Verify reports it, and rebuilds the keys reachable from
+// the root into a fresh trie made by New(opt), with their payloads and posting
+// lists. The files in `dir` are only read, `opt.MMapPath` must be another
+// directory. The keys are found by the `check` of the nodes rather than by the
+// sibling chains, a broken edge only loses the subtree below it, which is
+// reported in the returned Lost. Binary is taken from the damaged trie.
+func Salvage(dir string, opt *Options) (*Cedar, []Lost, error) {
+	o := *opt
+	if o.UseMMap {
+		src, _ := filepath.Abs(dir)
+		dst, _ := filepath.Abs(o.MMapPath)
+		if src == dst {
+			return nil, nil, ErrSalvageDir
+		}
+	}
+
+	s, err := readSalvager(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	o.Binary = s.cd.binary
+	dst := New(&o)
+	s.walk(0, nil, func(key []byte, val int, to int) {
+		// a damaged key or value may not be stored again.
+		origin := s.origin(key, to)
+		if bytes.IndexByte(dst.escape(origin), 0) >= 0 {
+			s.lose(to, "the key holds the byte 0")
+			return
+		}
+		if err := dst.Insert(origin, val); err != nil {
+			s.lose(to, err.Error())
+			return
+		}
+		at, err := dst.valueNode(dst.escape(origin))
+		if err != nil {
+			s.lose(to, err.Error())
+			return
+		}
+
+		if data := s.record(0, to); data != nil {
+			p := dst.payloads()
+			p.setRef(at, p.put(0, data))
+		}
+		if data := s.record(1, to); data != nil {
+			p := dst.postingLists()
+			p.setRef(at, p.put(0, data))
+		}
+	})
+	s.orphans()
+	return dst, s.lost, nil
+}
+
+// readSalvager loads the files of the trie in `dir` to the heap, cutting them
+// to the nodes held by all of them and in use.
+func readSalvager(dir string) (*salvager, error) {
+	read := func(name string) []byte {
+		data, _ := os.ReadFile(path.Join(dir, name))
+		return data
+	}
+
+	block := read(blockFileName)
+	if len(block) < metaSize {
+		return nil, corrupt("no meta info in %s", dir)
+	}
+	meta := *(*MetaInfo)(unsafe.Pointer(&block[0]))
+	meta.useMMap = false
+
+	array, nInfo := read(arrayFileName), read(nInfoFileName)
+	n := len(array) / nodeSize
+	if m := len(nInfo) / nInfoSize; m < n {
+		n = m
+	}
+	// the nodes past the size are not initialized.
+	if meta.size > 0 && meta.size < n {
+		n = meta.size
+	}
+	if n == 0 {
+		return nil, corrupt("no nodes in %s", dir)
+	}
+
+	// the tries written before the file `meta` hold no binary keys.
+	var ext metaExt
+	if data := read(metaFileName); len(data) >= extSize {
+		ext = *(*metaExt)(unsafe.Pointer(&data[0]))
+	}
+
+	cd := &Cedar{MetaInfo: &meta, metaExt: &ext}
+	cd.array = unsafe.Slice((*Node)(unsafe.Pointer(&array[0])), n)
+	cd.nInfos = unsafe.Slice((*NInfo)(unsafe.Pointer(&nInfo[0])), n)
+	cd.size, cd.capacity = n, n
+	if tail := read(tailFileName); len(tail) >= tailHeader {
+		cd.tail = &tailPool{region: &region{data: tail}}
+	}
+
+	s := &salvager{cd: cd, reached: make([]bool, n)}
+	for i, name := range []string{payloadFileName, postingFileName, originFileName} {
+		s.blobs[i], s.refs[i] = read(name), read(name+refFileSuffix)
+	}
+	return s, nil
+}
+
+// lose records the subtree below the node `to`, with the path to it or to
+// its parent if either is reached.
+func (s *salvager) lose(to int, reason string) {
+	at := to
+	if from := s.cd.array[to].check; !s.reached[at] && from >= 0 && from < len(s.reached) {
+		at = from
+	}
+
+	var prefix []byte
+	if s.reached[at] {
+		prefix = s.cd.unescape(s.path(at))
+	}
+	s.lost = append(s.lost, Lost{Node: to, Prefix: prefix, Reason: reason})
+}
+
+// walk calls fn with every key below the node `from` it can read, with its
+// value and the node holding the value.
+func (s *salvager) walk(from int, key []byte, fn func(key []byte, val, to int)) {
+	cd := s.cd
+	s.reached[from] = true
+
+	n := cd.array[from]
+	if cd.Reduced && n.baseV >= 0 && from != 0 {
+		s.leaf(from, key, fn)
+		return
+	}
+
+	base := n.base(cd.Reduced)
+	if base < 0 {
+		return
+	}
+
+	// the children are found by their check, the labels of the sibling chain
+	// left are the broken edges.
+	chain := s.chain(from, base)
+	for label := 0; label < 256; label++ {
+		to := base ^ label
+		if to == 0 || to >= len(cd.array) || cd.array[to].check != from {
+			continue
+		}
+		chain[label] = false
+
+		if label == 0 {
+			s.reached[to] = true
+			if val := cd.array[to].baseV; val != ValLimit {
+				s.value(key, val, to, fn)
+			}
+		} else {
+			s.walk(to, append(key, byte(label)), fn)
+		}
+	}
+
+	for label, broken := range chain {
+		if broken {
+			s.lose(from, fmt.Sprintf("the edge %d is broken", label))
+		}
+	}
+}
+
+// leaf calls fn with the key ending at the reduced leaf `to`.
+func (s *salvager) leaf(to int, key []byte, fn func(key []byte, val, to int)) {
+	v := s.cd.array[to].baseV
+	if v == ValLimit {
+		return
+	}
+	if s.cd.tail == nil {
+		fn(key, v, to)
+		return
+	}
+
+	// the stored keys never hold the byte 0 of the terminal nodes.
+	suffix, ok := s.tailEntry(v)
+	switch {
+	case !ok:
+		s.lose(to, "the tail entry is out of the pool")
+	case bytes.IndexByte(suffix, 0) >= 0:
+		s.lose(to, "the tail entry holds the byte 0")
+	default:
+		fn(append(key, suffix...), s.cd.tail.value(v), to)
+	}
+}
+
+// value calls fn with the key ending at the terminal node `to`.
+func (s *salvager) value(key []byte, v, to int, fn func(key []byte, val, to int)) {
+	if s.cd.tail == nil {
+		fn(key, v, to)
+	} else if _, ok := s.tailEntry(v); ok {
+		fn(key, s.cd.tail.value(v), to)
+	} else {
+		s.lose(to, "the tail entry is out of the pool")
+	}
+}
+
+func (s *salvager) tailEntry(off int) ([]byte, bool) {
+	data := s.cd.tail.data
+	if off < tailHeader || off > len(data)-tailEntryHeader {
+		return nil, false
+	}
+	n := int(binary.LittleEndian.Uint32(data[off+8:]))
+	if n > len(data)-off-tailEntryHeader {
+		return nil, false
+	}
+	suffix, _ := s.cd.tail.entry(off)
+	return suffix, true
+}
+
+// chain returns the labels but the terminal one in the sibling chain of
+// `from`, giving up at the first one out of the array or seen before.
+func (s *salvager) chain(from, base int) []bool {
+	labels := make([]bool, 256)
+	c := s.cd.nInfos[from].child
+	if c == 0 && base < len(s.cd.nInfos) {
+		c = s.cd.nInfos[base].sibling
+	}
+	for ; c != 0 && !labels[c] && base^int(c) < len(s.cd.nInfos); c = s.cd.nInfos[base^int(c)].sibling {
+		labels[c] = true
+	}
+	return labels
+}
+
+// path returns the key leading to the reached node `to`.
+func (s *salvager) path(to int) []byte {
+	cd := s.cd
+	var key []byte
+	for n := to; n > 0; n = cd.array[n].check {
+		if label := byte(n ^ cd.array[cd.array[n].check].base(cd.Reduced)); label != 0 {
+			key = append(key, label)
+		}
+	}
+	for i, j := 0, len(key)-1; i < j; i, j = i+1, j-1 {
+		key[i], key[j] = key[j], key[i]
+	}
+	return key
+}
+
+// orphans records the nodes in use not reached from the root, whose parent is
+// reached or is not a node in use, which are the tops of the lost subtrees.
+func (s *salvager) orphans() {
+	cd := s.cd
+	for i := 1; i < len(cd.array); i++ {
+		from := cd.array[i].check
+		if s.reached[i] || from < 0 {
+			continue
+		}
+
+		switch {
+		case from >= len(cd.array) || from != 0 && cd.array[from].check < 0:
+			s.lose(i, "the parent is not in use")
+		case s.reached[from]:
+			s.lose(i, "the parent does not lead to it")
+		}
+	}
+}
+
+// record returns the record of the store `i` for the node `to`, nil if none or
+// if it is out of the store.
+func (s *salvager) record(i, to int) []byte {
+	refs, blobs := s.refs[i], s.blobs[i]
+	if to*refSize+refSize > len(refs) {
+		return nil
+	}
+
+	off := int(binary.LittleEndian.Uint64(refs[to*refSize:]))
+	if off < payloadHeader || off > len(blobs)-payloadEntryHeader {
+		return nil
+	}
+	n := int(binary.LittleEndian.Uint64(blobs[off+8:]))
+	if n < 0 || n > len(blobs)-off-payloadEntryHeader {
+		return nil
+	}
+	return blobs[off+payloadEntryHeader : off+payloadEntryHeader+n]
+}
+
+// origin returns the key given for the stored key `key`.
+func (s *salvager) origin(key []byte, to int) []byte {
+	if data := s.record(2, to); data != nil {
+		return data
+	}
+	return s.cd.unescape(key)
+}
@@ -0,0 +1,63 @@
+package gocedar
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"unsafe"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestSalvage(t *testing.T) {
+	dir := t.TempDir()
+	cd := New(&Options{Reduced: true, UseMMap: true, MMapPath: dir})
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("%c-%d", 'a'+i%5, i))
+		tt.Nil(t, cd.Insert(key, i))
+		tt.Nil(t, cd.PutPayload(key, key))
+	}
+	from, err := cd.Jump([]byte("c-"), 0)
+	tt.Nil(t, err)
+	cd.Close()
+
+	_, _, err = Salvage(dir, &Options{UseMMap: true, MMapPath: dir})
+	tt.Equal(t, ErrSalvageDir, err)
+
+	sd, lost, err := Salvage(dir, &Options{Reduced: true})
+	tt.Nil(t, err)
+	tt.Equal(t, 0, len(lost))
+	tt.Equal(t, 1000, sd.Len())
+	tt.Nil(t, sd.Verify())
+	data, err := sd.GetPayload([]byte("b-1"))
+	tt.Nil(t, err)
+	tt.Equal(t, "b-1", string(data))
+
+	// break the check of the node below "c-".
+	f, err := os.OpenFile(path.Join(dir, arrayFileName), os.O_RDWR, fileMode)
+	tt.Nil(t, err)
+	check := make([]byte, unsafe.Sizeof(0))
+	_, err = f.WriteAt(check, int64(from*nodeSize)+int64(unsafe.Offsetof(Node{}.check)))
+	tt.Nil(t, err)
+	tt.Nil(t, f.Close())
+
+	cd = New(&Options{Reduced: true, UseMMap: true, MMapPath: dir})
+	tt.True(t, errors.Is(cd.Verify(), ErrCorrupt))
+	cd.Close()
+
+	sd, lost, err = Salvage(dir, &Options{Reduced: true, UseMMap: true, MMapPath: t.TempDir()})
+	tt.Nil(t, err)
+	defer sd.Close()
+	tt.Nil(t, sd.Verify())
+	tt.Equal(t, 800, sd.Len())
+	tt.True(t, len(lost) > 0)
+	tt.Equal(t, "c", string(lost[0].Prefix))
+
+	_, err = sd.Get([]byte("c-2"))
+	tt.NotNil(t, err)
+	val, err := sd.Get([]byte("d-3"))
+	tt.Nil(t, err)
+	tt.Equal(t, 3, val)
+}
@@ -0,0 +1,119 @@
+package gocedar
+
+// Stats describes the size and the shape of a trie, see Cedar.Stats.
+type Stats struct {
+	Keys     int // the number of keys
+	Nodes    int // the nodes in use, including the root
+	Size     int // the nodes in the blocks taken, used or free
+	Capacity int // the nodes the backing arrays hold
+	Free     int // the free nodes in the blocks taken
+
+	FullBlocks   int // the blocks without a free node
+	ClosedBlocks int // the blocks with one free node, or given up by the search of free nodes
+	OpenBlocks   int // the blocks with free nodes
+
+	FanOut   float64 // the average number of children of a node with children
+	MaxDepth int     // the most edges from the root to a node
+
+	// Bytes holds the bytes of every backing file, or of the arrays kept in
+	// their place on the heap, by the name of the file.
+	Bytes map[string]int
+
+	Resolves  int // the conflicts met inserting since the trie was made or loaded
+	Relocated int // the nodes moved to resolve the conflicts
+}
+
+// Fill returns the fraction of the nodes in use among the nodes of the blocks
+// taken, a low one tells Compact would reclaim much.
+func (s Stats) Fill() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return float64(s.Nodes) / float64(s.Size)
+}
+
+// Stats returns the size and the shape of the trie, it visits every node.
+func (cd *Cedar) Stats() Stats {
+	s := Stats{
+		Keys:      cd.keys,
+		Size:      cd.size,
+		Capacity:  cd.capacity,
+		Resolves:  cd.resolves,
+		Relocated: cd.relocated,
+	}
+
+	for i := 0; i < cd.size; i++ {
+		if cd.used(i) {
+			s.Nodes++
+		}
+	}
+	s.Free = cd.size - s.Nodes
+
+	s.FullBlocks = cd.countBlocks(cd.blocksHeadFull)
+	s.ClosedBlocks = cd.countBlocks(cd.blocksHeadClosed)
+	s.OpenBlocks = cd.countBlocks(cd.blocksHeadOpen)
+
+	parents, edges := cd.shape(0, 0, &s.MaxDepth)
+	if parents > 0 {
+		s.FanOut = float64(edges) / float64(parents)
+	}
+
+	s.Bytes = map[string]int{
+		arrayFileName: cd.capacity * nodeSize,
+		blockFileName: metaSize + cd.capacity>>8*blockSize,
+		nInfoFileName: cd.capacity * nInfoSize,
+		metaFileName:  extSize,
+	}
+	if cd.tail != nil {
+		s.Bytes[tailFileName] = len(cd.tail.data)
+	}
+	names := []string{payloadFileName, postingFileName, originFileName}
+	for i, p := range cd.stores() {
+		if p != nil {
+			s.Bytes[names[i]] = len(p.blobs.data)
+			s.Bytes[names[i]+refFileSuffix] = len(p.refs.data)
+		}
+	}
+
+	return s
+}
+
+// countBlocks returns the number of blocks in the list starting at `head`,
+// leaving out the first block which transferBlock may link into the lists.
+func (cd *Cedar) countBlocks(head int) int {
+	if head == 0 {
+		return 0
+	}
+
+	n := 0
+	for idx := head; ; {
+		if idx != 0 {
+			n++
+		}
+		if idx = cd.blocks[idx].next; idx == head {
+			return n
+		}
+	}
+}
+
+// shape returns the number of nodes with children below `from` and of their
+// children, and raises `maxDepth` to the depth of the deepest node.
+func (cd *Cedar) shape(from, depth int, maxDepth *int) (parents, edges int) {
+	if depth > *maxDepth {
+		*maxDepth = depth
+	}
+
+	n := 0
+	cd.children(from, func(label byte, to int) bool {
+		n++
+		if label != 0 {
+			p, e := cd.shape(to, depth+1, maxDepth)
+			parents, edges = parents+p, edges+e
+		}
+		return true
+	})
+	if n > 0 {
+		parents, edges = parents+1, edges+n
+	}
+	return parents, edges
+}
@@ -0,0 +1,41 @@
+package gocedar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestStats(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	s := cd.Stats()
+	tt.Equal(t, 0, s.Keys)
+	tt.Equal(t, 1, s.Nodes)
+	tt.Equal(t, 255, s.Free)
+	tt.Equal(t, 0, s.MaxDepth)
+
+	for i := 0; i < 3000; i++ {
+		tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("key-%d", i)), i))
+	}
+	tt.Nil(t, cd.Insert([]byte("key-1234567890"), 1))
+
+	s = cd.Stats()
+	tt.Equal(t, 3001, s.Keys)
+	tt.Equal(t, cd.size, s.Nodes+s.Free)
+	tt.Equal(t, cd.size>>8-1, s.FullBlocks+s.ClosedBlocks+s.OpenBlocks)
+	tt.Equal(t, 14, s.MaxDepth)
+	tt.True(t, s.FanOut > 1)
+	tt.True(t, s.Resolves > 0)
+	tt.True(t, s.Relocated >= s.Resolves)
+	tt.Equal(t, cd.capacity*nodeSize, s.Bytes[arrayFileName])
+	tt.True(t, s.Fill() > 0 && s.Fill() <= 1)
+
+	for i := 0; i < 3000; i += 2 {
+		tt.Nil(t, cd.Delete([]byte(fmt.Sprintf("key-%d", i))))
+	}
+	fill := cd.Stats().Fill()
+	_, err := cd.Compact()
+	tt.Nil(t, err)
+	tt.True(t, cd.Stats().Fill() > fill)
+}
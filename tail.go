@@ -0,0 +1,162 @@
+package gocedar
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	tailHeader      = 16 // the used bytes and the garbage bytes of the pool
+	tailEntryHeader = 12 // the value (8 bytes) and the length of the suffix (4 bytes)
+)
+
+// tailPool stores the suffixes only used by one key, as in the TAIL of the
+// original double-array papers. With Tail every leaf holds the offset of its
+// entry in the pool instead of the value, and the entry holds the value and
+// the rest of the key below the leaf.
+type tailPool struct {
+	*region
+}
+
+func newTailPool(r *region) *tailPool {
+	p := &tailPool{region: r}
+	if len(r.data) == 0 {
+		r.reserve(tailHeader)
+		p.setUsed(tailHeader)
+	}
+	return p
+}
+
+// used returns the bytes of the pool in use, including the garbage.
+func (p *tailPool) used() int {
+	return int(binary.LittleEndian.Uint64(p.data))
+}
+
+func (p *tailPool) setUsed(n int) {
+	binary.LittleEndian.PutUint64(p.data, uint64(n))
+}
+
+// garbage returns the bytes of the pool no longer referenced by a leaf.
+func (p *tailPool) garbage() int {
+	return int(binary.LittleEndian.Uint64(p.data[8:]))
+}
+
+func (p *tailPool) addGarbage(n int) {
+	binary.LittleEndian.PutUint64(p.data[8:], uint64(p.garbage()+n))
+}
+
+// add appends an entry and returns its offset. It may remap the pool,
+// the suffixes returned by `entry` before are no longer valid.
+func (p *tailPool) add(suffix []byte, val int) int {
+	off := p.used()
+	p.reserve(off + tailEntryHeader + len(suffix))
+	binary.LittleEndian.PutUint64(p.data[off:], uint64(val))
+	binary.LittleEndian.PutUint32(p.data[off+8:], uint32(len(suffix)))
+	copy(p.data[off+tailEntryHeader:], suffix)
+	p.setUsed(off + tailEntryHeader + len(suffix))
+
+	return off
+}
+
+// entry returns the suffix and the value of the entry at `off`.
+func (p *tailPool) entry(off int) (suffix []byte, val int) {
+	n := int(binary.LittleEndian.Uint32(p.data[off+8:]))
+	return p.data[off+tailEntryHeader : off+tailEntryHeader+n], p.value(off)
+}
+
+func (p *tailPool) value(off int) int {
+	return int(binary.LittleEndian.Uint64(p.data[off:]))
+}
+
+func (p *tailPool) setValue(off, val int) {
+	binary.LittleEndian.PutUint64(p.data[off:], uint64(val))
+}
+
+// shift drops the first byte of the suffix of the entry at `off` in place.
+func (p *tailPool) shift(off int) {
+	suffix, _ := p.entry(off)
+	copy(suffix, suffix[1:])
+	binary.LittleEndian.PutUint32(p.data[off+8:], uint32(len(suffix)-1))
+	p.addGarbage(1)
+}
+
+// free marks the entry at `off` as garbage, the space is reclaimed by `Compact`.
+func (p *tailPool) free(off int) {
+	suffix, _ := p.entry(off)
+	p.addGarbage(tailEntryHeader + len(suffix))
+}
+
+// leafValue resolves the `baseV` of a leaf to its value, through the tail
+// entry when Tail is enabled.
+func (cd *Cedar) leafValue(v int) int {
+	if cd.tail == nil || v == ValLimit {
+		return v
+	}
+	return cd.tail.value(v)
+}
+
+// tailGet returns the node holding the entry of `key`, the key is inserted
+// with the value ValLimit if it is not there. It is the `getNode` of Tail,
+// starting at the node `from` reached by the first `pos` bytes of the key.
+func (cd *Cedar) tailGet(key []byte, from, pos int) int {
+	for {
+		if v := cd.array[from].baseV; v >= 0 && v != ValLimit {
+			suffix, _ := cd.tail.entry(v)
+			if bytes.Equal(suffix, key[pos:]) {
+				return from
+			}
+			// the key branches off inside the tail.
+			cd.pushTail(from)
+			continue
+		}
+
+		if pos == len(key) {
+			break
+		}
+
+		to := cd.array[from].base(cd.Reduced) ^ int(key[pos])
+		if cd.array[from].baseV >= 0 || cd.array[to].check != from {
+			// the rest of the key goes to the tail of a new leaf.
+			to = cd.follow(from, key[pos])
+			cd.array[to].baseV = cd.tail.add(key[pos+1:], ValLimit)
+			return to
+		}
+		from = to
+		pos++
+	}
+
+	to := cd.follow(from, 0)
+	if cd.array[to].baseV == ValLimit {
+		cd.array[to].baseV = cd.tail.add(nil, ValLimit)
+	}
+	return to
+}
+
+// copyTails adds the entries of the leaves of `shard` to the pool, and points
+// the leaves to the copies, leaving the garbage of the shard behind.
+func (cd *Cedar) copyTails(shard *Cedar) {
+	for i := 0; i < shard.size; i++ {
+		n := &shard.array[i]
+		if n.check >= 0 && n.baseV >= 0 && n.baseV != ValLimit {
+			n.baseV = cd.tail.add(shard.tail.entry(n.baseV))
+		}
+	}
+}
+
+// pushTail moves the entry of the leaf `from` one level down, under the first
+// byte of its suffix, or under the terminal label 0 if the suffix is empty.
+func (cd *Cedar) pushTail(from int) {
+	off := cd.array[from].baseV
+	suffix, _ := cd.tail.entry(off)
+
+	label := byte(0)
+	if len(suffix) > 0 {
+		label = suffix[0]
+		cd.tail.shift(off)
+	}
+
+	cd.array[from].baseV = ValLimit
+	to := cd.follow(from, label)
+	cd.array[to].baseV = off
+	cd.moveValue(to)
+}
@@ -0,0 +1,49 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestTail(t *testing.T) {
+	cd := New(&Options{Tail: true})
+	tt.True(t, cd.Reduced)
+
+	keys := []string{"http://a.com/x/y", "http://a.com/x/z", "http://b.com", "http", "梦", "夜长梦多"}
+	for i, key := range keys {
+		tt.Nil(t, cd.Insert([]byte(key), i))
+	}
+	for i, key := range keys {
+		val, err := cd.Get([]byte(key))
+		tt.Nil(t, err)
+		tt.Equal(t, i, val)
+	}
+
+	// the middle of a tail is not a key.
+	_, err := cd.Get([]byte("http://b"))
+	tt.NotNil(t, err)
+	_, err = cd.Find([]byte("夜长"), 0)
+	tt.NotNil(t, err)
+
+	tt.Equal(t, 3, len(cd.PrefixPredict([]byte("http://"))))
+	tt.Equal(t, 1, len(cd.PrefixPredict([]byte("http://b"))))
+	tt.Equal(t, 2, len(cd.PrefixMatch([]byte("http://b.com/index"))))
+
+	tt.Nil(t, cd.Update([]byte("梦"), 10))
+	val, err := cd.Get([]byte("梦"))
+	tt.Nil(t, err)
+	tt.Equal(t, 14, val)
+
+	tt.Nil(t, cd.Delete([]byte("http://a.com/x/y")))
+	tt.NotNil(t, cd.Delete([]byte("http://a.com/x")))
+	_, err = cd.Compact()
+	tt.Nil(t, err)
+	tt.Equal(t, 0, cd.tail.garbage())
+
+	_, err = cd.Get([]byte("http://a.com/x/y"))
+	tt.NotNil(t, err)
+	val, err = cd.Get([]byte("http://a.com/x/z"))
+	tt.Nil(t, err)
+	tt.Equal(t, 1, val)
+}
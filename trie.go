@@ -0,0 +1,110 @@
+package gocedar
+
+import "errors"
+
+var errStop = errors.New("cedar: stop walking")
+
+// Trie maps keys to values of any type. The values are kept in an arena on
+// the heap, and the underlying Cedar stores the index of the value of each
+// key in the arena. Use Cedar itself to keep int values in mmap.
+type Trie[V any] struct {
+	cd   *Cedar
+	vals []V
+	free []int // the indexes of the arena released by Delete
+}
+
+// NewTrie initialize the Trie for further use, `opt` may be nil. The trie is
+// always Reduced and lives on the heap, UseMMap is ignored.
+func NewTrie[V any](opt *Options) *Trie[V] {
+	o := Options{Reduced: true}
+	if opt != nil {
+		o = *opt
+		o.Reduced, o.UseMMap = true, false
+	}
+
+	return &Trie[V]{cd: New(&o)}
+}
+
+// Len returns the number of keys in the trie.
+func (t *Trie[V]) Len() int {
+	return t.cd.Len()
+}
+
+// Insert the key for the value, overwriting the value if the key is there.
+func (t *Trie[V]) Insert(key []byte, val V) error {
+	if i, err := t.cd.Get(key); err == nil {
+		t.vals[i] = val
+		return nil
+	}
+
+	i := len(t.vals)
+	if n := len(t.free); n > 0 {
+		i, t.free = t.free[n-1], t.free[:n-1]
+		t.vals[i] = val
+	} else {
+		t.vals = append(t.vals, val)
+	}
+
+	return t.cd.Insert(key, i)
+}
+
+// Get get the value of the key
+func (t *Trie[V]) Get(key []byte) (val V, err error) {
+	i, err := t.cd.Get(key)
+	if err != nil {
+		return val, err
+	}
+
+	return t.vals[i], nil
+}
+
+// Delete the key from the trie, and release its value.
+func (t *Trie[V]) Delete(key []byte) error {
+	i, err := t.cd.Get(key)
+	if err != nil {
+		return err
+	}
+	if err = t.cd.Delete(key); err != nil {
+		return err
+	}
+
+	var zero V
+	t.vals[i] = zero
+	t.free = append(t.free, i)
+
+	return nil
+}
+
+// PrefixMatch return the values of the keys in the trie which are
+// prefixes of `key`
+func (t *Trie[V]) PrefixMatch(key []byte, n ...int) []V {
+	return t.values(t.cd.PrefixMatch(key, n...))
+}
+
+// PrefixPredict return the values of the keys in the trie
+// that has `key` as their prefix
+func (t *Trie[V]) PrefixPredict(key []byte, n ...int) []V {
+	return t.values(t.cd.PrefixPredict(key, n...))
+}
+
+func (t *Trie[V]) values(ids []int) []V {
+	vals := make([]V, 0, len(ids))
+	for _, id := range ids {
+		if i, err := t.cd.Value(id); err == nil {
+			vals = append(vals, t.vals[i])
+		}
+	}
+
+	return vals
+}
+
+// Walk calls fn with every key and value in the order of the keys, until fn
+// returns false. The key passed to fn is reused, fn must copy it to keep it.
+func (t *Trie[V]) Walk(fn func(key []byte, val V) bool) {
+	_ = t.cd.walk(0, nil, func(key []byte, i int) error {
+		if !fn(t.cd.original(key), t.vals[i]) {
+			return errStop
+		}
+		return nil
+	})
+}
@@ -0,0 +1,49 @@
+package gocedar
+
+import (
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+type song struct {
+	title  string
+	tracks []int
+}
+
+func TestTrie(t *testing.T) {
+	tr := NewTrie[song](nil)
+	for i, word := range words {
+		tt.Nil(t, tr.Insert([]byte(word), song{title: word, tracks: []int{i}}))
+	}
+	tt.Equal(t, len(words), tr.Len())
+
+	s, err := tr.Get([]byte("梦"))
+	tt.Nil(t, err)
+	tt.Equal(t, "梦", s.title)
+
+	tt.Nil(t, tr.Insert([]byte("梦"), song{title: "梦", tracks: []int{4, 5}}))
+	s, _ = tr.Get([]byte("梦"))
+	tt.Equal(t, 2, len(s.tracks))
+
+	tt.Nil(t, tr.Delete([]byte("夜长梦多")))
+	_, err = tr.Get([]byte("夜长梦多"))
+	tt.NotNil(t, err)
+	tt.Nil(t, tr.Insert([]byte("夜长梦多"), song{title: "again"}))
+	tt.Equal(t, len(words), len(tr.vals))
+
+	tt.Nil(t, tr.Insert([]byte("活得好"), song{title: "活得好"}))
+	tt.Equal(t, 2, len(tr.PrefixPredict([]byte("活得"))))
+	tt.Equal(t, 1, len(tr.PrefixPredict([]byte("活得"), 1)))
+	tt.Equal(t, 1, len(tr.PrefixMatch([]byte("梦想"))))
+
+	n := 0
+	prev := ""
+	tr.Walk(func(key []byte, s song) bool {
+		tt.True(t, prev < string(key))
+		prev = string(key)
+		n++
+		return n < 3
+	})
+	tt.Equal(t, 3, n)
+}
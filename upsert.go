@@ -0,0 +1,67 @@
+package gocedar
+
+// Upsert calls fn with the value of the key and whether the key is there, and
+// stores the value fn returns if it also returns true. It walks the trie only
+// once, and reports whether the key was there before. The trie is not changed
+// unless fn stores the value.
+func (cd *Cedar) Upsert(key []byte, fn func(old int, exists bool) (int, bool)) (bool, error) {
+	origin := key
+	key = cd.escape(key)
+
+	// the walk stops where a new key leaves the trie, its nodes are made from
+	// there only once fn stores it.
+	to, pos, exists := cd.lookup(key)
+	old := ValLimit
+	if exists {
+		old = cd.leafValue(cd.array[to].baseV)
+	}
+
+	val, ok := fn(old, exists)
+	if !ok {
+		return exists, nil
+	}
+	if val == ValLimit {
+		return exists, ErrInvalidVal
+	}
+
+	switch {
+	case !exists:
+		to = cd.insertAt(key, to, pos, val)
+	case cd.tail != nil:
+		cd.tail.setValue(cd.array[to].baseV, val)
+	default:
+		to = cd.setValue(to, val)
+	}
+	cd.keepOrigin(to, origin)
+	return exists, nil
+}
+
+// CompareAndSwap stores `new` to the key if its value is `old`, and reports
+// whether it did.
+func (cd *Cedar) CompareAndSwap(key []byte, old, new int) (bool, error) {
+	swapped := false
+	exists, err := cd.Upsert(key, func(val int, exists bool) (int, bool) {
+		swapped = exists && val == old
+		return new, swapped
+	})
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrNoKey
+	}
+	return swapped, nil
+}
+
+// GetOrInsert returns the value of the key and true if the key is there,
+// otherwise it inserts the key for `val` and returns `val` and false.
+func (cd *Cedar) GetOrInsert(key []byte, val int) (int, bool, error) {
+	actual := val
+	exists, err := cd.Upsert(key, func(old int, exists bool) (int, bool) {
+		if exists {
+			actual = old
+		}
+		return val, !exists
+	})
+	return actual, exists, err
+}
@@ -0,0 +1,97 @@
+package gocedar
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestUpsert(t *testing.T) {
+	cd := New(&Options{Reduced: true})
+	incr := func(old int, exists bool) (int, bool) {
+		return old + 1, true
+	}
+	for i := 0; i < 3; i++ {
+		exists, err := cd.Upsert([]byte("counter"), func(old int, exists bool) (int, bool) {
+			if !exists {
+				return 1, true
+			}
+			return incr(old, exists)
+		})
+		tt.Nil(t, err)
+		tt.Equal(t, i > 0, exists)
+	}
+	val, err := cd.Get([]byte("counter"))
+	tt.Nil(t, err)
+	tt.Equal(t, 3, val)
+
+	// nothing is left behind when fn does not store.
+	exists, err := cd.Upsert([]byte("count"), func(int, bool) (int, bool) { return 0, false })
+	tt.Nil(t, err)
+	tt.False(t, exists)
+	_, err = cd.Get([]byte("count"))
+	tt.NotNil(t, err)
+	tt.Equal(t, 1, cd.Len())
+
+	swapped, err := cd.CompareAndSwap([]byte("counter"), 2, 10)
+	tt.Nil(t, err)
+	tt.False(t, swapped)
+	swapped, err = cd.CompareAndSwap([]byte("counter"), 3, 10)
+	tt.Nil(t, err)
+	tt.True(t, swapped)
+	// a missing key leaves the trie and its cursors as they were.
+	c := cd.Cursor()
+	tt.Nil(t, c.StepBytes([]byte("count")))
+	_, err = cd.CompareAndSwap([]byte("missing"), 0, 1)
+	tt.Equal(t, ErrNoKey, err)
+	_, err = cd.CompareAndSwap([]byte("counter-x"), 0, 1)
+	tt.Equal(t, ErrNoKey, err)
+	tt.Nil(t, c.Step('e'))
+	tt.Nil(t, cd.Verify())
+
+	val, loaded, err := cd.GetOrInsert([]byte("counter"), 0)
+	tt.Nil(t, err)
+	tt.True(t, loaded)
+	tt.Equal(t, 10, val)
+	val, loaded, err = cd.GetOrInsert([]byte("dedup"), -1)
+	tt.Nil(t, err)
+	tt.False(t, loaded)
+	tt.Equal(t, -1, val)
+	tt.Equal(t, 2, cd.Len())
+
+	// the tails are left as they were when fn does not store.
+	cd = New(&Options{Tail: true})
+	tt.Nil(t, cd.Insert([]byte("http://a.com/x"), 1))
+	used := cd.tail.used()
+	exists, err = cd.Upsert([]byte("http://a.com/y"), func(int, bool) (int, bool) { return 0, false })
+	tt.Nil(t, err)
+	tt.False(t, exists)
+	tt.Equal(t, used, cd.tail.used())
+	exists, err = cd.Upsert([]byte("http://a.com/x"), func(old int, _ bool) (int, bool) { return old - 5, true })
+	tt.Nil(t, err)
+	tt.True(t, exists)
+	val, err = cd.Get([]byte("http://a.com/x"))
+	tt.Nil(t, err)
+	tt.Equal(t, -4, val)
+	tt.Nil(t, cd.Verify())
+}
+
+func TestUpsertNewKeys(t *testing.T) {
+	// the new keys are inserted from where the lookup left the trie: inside a
+	// tail, below a leaf, at the root, or past a terminal node.
+	keys := []string{"周", "周杰伦", "周杰", "周华健", "", "周杰伦x", "a", "ab", "b"}
+	for _, o := range []Options{{}, {Reduced: true}, {Tail: true}, {Counts: true}} {
+		cd := New(&o)
+		for round := 0; round < 2; round++ {
+			for i, key := range keys {
+				val, loaded, err := cd.GetOrInsert([]byte(key), i-4)
+				tt.Nil(t, err)
+				tt.Equal(t, round > 0, loaded, fmt.Sprintf("%v %q", o, key))
+				tt.Equal(t, i-4, val)
+			}
+		}
+		tt.Equal(t, len(keys), cd.Len())
+		tt.Nil(t, cd.Verify())
+	}
+}
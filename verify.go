@@ -0,0 +1,279 @@
+package gocedar
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrCorrupt the trie breaks an invariant of the double array
+var ErrCorrupt = errors.New("cedar: corrupted trie")
+
+func corrupt(format string, v ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrCorrupt}, v...)...)
+}
+
+// Verify checks the invariants of the trie, for the tries loaded from mmap
+// files which may be damaged. It returns an error wrapping ErrCorrupt for the
+// first one broken:
+//
+//   - every used node is owned by a parent whose base leads to it,
+//   - the sibling chains list the children of every node once, in order,
+//   - every node is reachable from the root,
+//   - the empty nodes of every block form a ring of `num` nodes,
+//   - every block is in the Full, Closed or Open list matching its free slots,
+//   - the number of keys and the counts are right.
+func (cd *Cedar) Verify() error {
+	if cd.size < 256 || cd.size > cd.capacity || cd.size%256 != 0 || len(cd.array) < cd.capacity ||
+		len(cd.nInfos) < cd.capacity || len(cd.blocks) < cd.capacity>>8 {
+		return corrupt("size %d, capacity %d", cd.size, cd.capacity)
+	}
+	if cd.counts != nil && len(cd.counts) < cd.size {
+		return corrupt("%d counts for %d nodes", len(cd.counts), cd.size)
+	}
+	if cd.tail != nil && (len(cd.tail.data) < tailHeader || cd.tail.used() < tailHeader || cd.tail.used() > len(cd.tail.data)) {
+		return corrupt("the tail pool uses %d of %d bytes", cd.tail.used(), len(cd.tail.data))
+	}
+
+	if err := cd.verifyNodes(); err != nil {
+		return err
+	}
+	if err := cd.verifyBlocks(); err != nil {
+		return err
+	}
+	return cd.verifyKeys()
+}
+
+// used reports whether the node `i` is in the trie, the root always is.
+func (cd *Cedar) used(i int) bool {
+	return i == 0 || cd.array[i].check >= 0
+}
+
+// interior reports whether the node `i` has a base, rather than a value.
+func (cd *Cedar) interior(i int) bool {
+	if i == 0 {
+		return true
+	}
+	if from := cd.array[i].check; from >= 0 && from < cd.size && cd.isTerminal(i) {
+		return false
+	}
+	return cd.array[i].baseV < 0 == cd.Reduced
+}
+
+func (cd *Cedar) verifyNodes() error {
+	// the number of children of every node, from the `check`.
+	owned := make([]int, cd.size)
+	for i := 1; i < cd.size; i++ {
+		if !cd.used(i) {
+			continue
+		}
+
+		from := cd.array[i].check
+		if from >= cd.size || !cd.used(from) || !cd.interior(from) {
+			return corrupt("node %d is owned by the node %d, not in use", i, from)
+		}
+		if base := cd.array[from].base(cd.Reduced); base < 0 || base^i >= 256 {
+			return corrupt("node %d is not a child of the node %d", i, from)
+		}
+		owned[from]++
+
+		v := cd.array[i].baseV
+		if cd.tail != nil && !cd.interior(i) && v != ValLimit && !cd.inTail(v) {
+			return corrupt("node %d points to the tail %d", i, v)
+		}
+	}
+
+	// walk the sibling chains from the root, which also finds the cycles of nodes
+	// owning each other away from the root.
+	reached := 1
+	queue := []int{0}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		labels, err := cd.chain(from)
+		if err != nil {
+			return err
+		}
+		base, prev := cd.array[from].base(cd.Reduced), -1
+		for _, label := range labels {
+			to := base ^ int(label)
+			if cd.array[to].check != from {
+				return corrupt("the child %d of the node %d is not owned by it", label, from)
+			}
+			if cd.ordered && int(label) <= prev {
+				return corrupt("the children of the node %d are not in order", from)
+			}
+			prev = int(label)
+			if label != 0 && cd.interior(to) {
+				queue = append(queue, to)
+			}
+		}
+		if n := len(labels); n != owned[from] {
+			return corrupt("the node %d owns %d nodes, with %d in its sibling chain", from, owned[from], n)
+		}
+		reached += len(labels)
+	}
+
+	used := 0
+	for i := 0; i < cd.size; i++ {
+		if cd.used(i) {
+			used++
+		}
+	}
+	if reached != used {
+		return corrupt("%d nodes are in use, %d are reachable from the root", used, reached)
+	}
+	return nil
+}
+
+// chain returns the labels in the sibling chain of the node `from`, the
+// terminal one first, checking every child is in the array and listed once.
+func (cd *Cedar) chain(from int) ([]byte, error) {
+	base := cd.array[from].base(cd.Reduced)
+	if base < 0 {
+		return nil, nil
+	}
+	if base >= cd.size {
+		return nil, corrupt("node %d has the base %d out of the array", from, base)
+	}
+
+	var labels []byte
+	var seen [256]bool
+	c := cd.nInfos[from].child
+	if c == 0 {
+		if cd.array[base].check == from {
+			labels, seen[0] = append(labels, 0), true
+		}
+		c = cd.nInfos[base].sibling
+	}
+	for ; c != 0; c = cd.nInfos[base^int(c)].sibling {
+		if seen[c] {
+			return nil, corrupt("the sibling chain of the node %d lists %d twice", from, c)
+		}
+		seen[c] = true
+		labels = append(labels, c)
+	}
+	return labels, nil
+}
+
+// inTail reports whether a whole tail entry is at `off` in the pool.
+func (cd *Cedar) inTail(off int) bool {
+	used := cd.tail.used()
+	if off < tailHeader || off > used-tailEntryHeader {
+		return false
+	}
+	n := int(binary.LittleEndian.Uint32(cd.tail.data[off+8:]))
+	return n <= used-off-tailEntryHeader
+}
+
+func (cd *Cedar) verifyBlocks() error {
+	for idx := 0; idx < cd.size>>8; idx++ {
+		free := 0
+		for e := idx << 8; e < (idx+1)<<8; e++ {
+			if !cd.used(e) {
+				free++
+			}
+		}
+
+		// the root is counted as a free slot of the first block.
+		b := cd.blocks[idx]
+		num := b.num
+		if idx == 0 {
+			num--
+		}
+		if num != free {
+			return corrupt("block %d has %d free nodes, not %d", idx, free, num)
+		}
+		if free == 0 {
+			continue
+		}
+
+		// go around the ring of the empty nodes from its head.
+		e, n := b.eHead, 0
+		for ; n < free; n++ {
+			if e < 0 || e>>8 != idx || cd.used(e) {
+				return corrupt("block %d has a broken ring of empty nodes at %d", idx, e)
+			}
+			next := -cd.array[e].check
+			if next < 0 || next >= cd.size || -cd.array[next].baseV != e {
+				return corrupt("block %d has a broken ring of empty nodes at %d", idx, e)
+			}
+			if e = next; e == b.eHead {
+				n++
+				break
+			}
+		}
+		if n != free || e != b.eHead {
+			return corrupt("block %d has %d empty nodes in its ring, not %d", idx, n, free)
+		}
+	}
+
+	// every block but the first is in one of the lists. The first block is never
+	// moved between the lists, but transferBlock may link it into the Full one.
+	seen := make([]bool, cd.size>>8)
+	lists := []struct {
+		name string
+		head int
+		ok   func(num int) bool
+	}{
+		{"Full", cd.blocksHeadFull, func(num int) bool { return num == 0 }},
+		{"Closed", cd.blocksHeadClosed, func(num int) bool { return num > 0 }},
+		{"Open", cd.blocksHeadOpen, func(num int) bool { return num > 0 }},
+	}
+	for _, list := range lists {
+		if list.head == 0 {
+			continue
+		}
+
+		idx := list.head
+		for {
+			if idx < 0 || idx >= len(seen) || seen[idx] || idx == 0 && list.name != "Full" {
+				return corrupt("the %s block list is broken at %d", list.name, idx)
+			}
+			seen[idx] = true
+
+			b := cd.blocks[idx]
+			if idx != 0 && !list.ok(b.num) {
+				return corrupt("block %d with %d free nodes is in the %s list", idx, b.num, list.name)
+			}
+			if b.next < 0 || b.next >= len(seen) || cd.blocks[b.next].prev != idx {
+				return corrupt("the %s block list is broken at %d", list.name, idx)
+			}
+			if idx = b.next; idx == list.head {
+				break
+			}
+		}
+	}
+	for idx := 1; idx < len(seen); idx++ {
+		if !seen[idx] {
+			return corrupt("block %d is in no block list", idx)
+		}
+	}
+	return nil
+}
+
+func (cd *Cedar) verifyKeys() error {
+	keys := 0
+	_ = cd.walk(0, nil, func([]byte, int) error {
+		keys++
+		return nil
+	})
+	if keys != cd.keys {
+		return corrupt("%d keys are in the trie, not %d", keys, cd.keys)
+	}
+
+	if cd.counts == nil {
+		return nil
+	}
+	counts := append([]int(nil), cd.counts...)
+	cd.recount()
+	for i := range counts {
+		if counts[i] != cd.counts[i] {
+			err := corrupt("node %d counts %d keys, not %d", i, counts[i], cd.counts[i])
+			copy(cd.counts, counts)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package gocedar
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/vcaesar/tt"
+)
+
+func TestVerify(t *testing.T) {
+	// the keys of a non-reduced trie end in the terminal nodes.
+	nr := New(&Options{})
+	for i := 0; i < 2000; i++ {
+		tt.Nil(t, nr.Insert([]byte(fmt.Sprintf("song-%d", i)), i))
+	}
+	for i := 0; i < 2000; i += 3 {
+		tt.Nil(t, nr.Delete([]byte(fmt.Sprintf("song-%d", i))))
+	}
+	tt.Nil(t, nr.Verify())
+	tt.Equal(t, 1333, nr.Len())
+
+	cd := New(&Options{Reduced: true, Counts: true})
+	for i := 0; i < 5000; i++ {
+		tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("song-%d", i*7)), i))
+	}
+	for i := 0; i < 5000; i += 3 {
+		tt.Nil(t, cd.Delete([]byte(fmt.Sprintf("song-%d", i*7))))
+	}
+	tt.Nil(t, cd.Verify())
+
+	_, err := cd.Compact()
+	tt.Nil(t, err)
+	tt.Nil(t, cd.Verify())
+
+	// break the ownership of a node.
+	to, err := cd.Jump([]byte("song-7"), 0)
+	tt.Nil(t, err)
+	cd.array[to].check++
+	err = cd.Verify()
+	tt.True(t, errors.Is(err, ErrCorrupt))
+	cd.array[to].check--
+	tt.Nil(t, cd.Verify())
+
+	// break the ring of the empty nodes.
+	cd.blocks[1].num++
+	tt.True(t, errors.Is(cd.Verify(), ErrCorrupt))
+	cd.blocks[1].num--
+
+	cd.keys++
+	tt.True(t, errors.Is(cd.Verify(), ErrCorrupt))
+}
+
+func TestVerifyRandom(t *testing.T) {
+	opts := []Options{{}, {Reduced: true}, {Tail: true}, {Counts: true}, {Reduced: true, Unordered: true}}
+	r := rand.New(rand.NewSource(1))
+	// the values written over the fields, in and out of the arrays.
+	values := func(size int) int {
+		switch r.Intn(4) {
+		case 0:
+			return r.Intn(size)
+		case 1:
+			return -r.Intn(size)
+		case 2:
+			return r.Intn(256)
+		}
+		return r.Int() - r.Int()
+	}
+
+	for run := 0; run < 3000; run++ {
+		o := opts[run%len(opts)]
+		cd := New(&o)
+		for i := 0; i < 200; i++ {
+			tt.Nil(t, cd.Insert([]byte(fmt.Sprintf("k%d/%x", i%7, i*31)), i))
+		}
+
+		size := cd.size
+		for n := 1 + r.Intn(3); n > 0; n-- {
+			i := r.Intn(size)
+			switch r.Intn(9) {
+			case 0:
+				cd.array[i].baseV = values(size)
+			case 1:
+				cd.array[i].check = values(size)
+			case 2:
+				cd.nInfos[i].child = byte(r.Intn(256))
+			case 3:
+				cd.nInfos[i].sibling = byte(r.Intn(256))
+			case 4:
+				cd.blocks[i>>8].eHead = values(size)
+			case 5:
+				cd.blocks[i>>8].num = values(size)
+			case 6:
+				cd.blocks[i>>8].next = values(size)
+			case 7:
+				cd.blocksHeadOpen = values(size)
+			case 8:
+				cd.size = values(size)
+			}
+		}
+
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					t.Fatalf("run %d: Verify panics: %v", run, v)
+				}
+			}()
+			if err := cd.Verify(); err != nil {
+				tt.True(t, errors.Is(err, ErrCorrupt))
+			}
+		}()
+	}
+}
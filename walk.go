@@ -0,0 +1,99 @@
+package gocedar
+
+import "sort"
+
+// children calls fn with the label and the node of every child of `from` in
+// the order of the labels, until fn returns false. The terminal node holding
+// the value of the key ending at `from` comes first, with the label 0.
+func (cd *Cedar) children(from int, fn func(label byte, to int) bool) {
+	n := cd.array[from]
+	if cd.Reduced && n.baseV >= 0 {
+		return
+	}
+
+	base := n.base(cd.Reduced)
+	if base < 0 {
+		return
+	}
+
+	// the child 0 means the sibling chain starts at the terminal node, or at the
+	// root itself since its `base` is 0.
+	c := cd.nInfos[from].child
+	if c == 0 {
+		if cd.array[base].check == from && !fn(0, base) {
+			return
+		}
+		c = cd.nInfos[base].sibling
+	}
+
+	// the sibling chain of an unordered trie is in the order the children
+	// were added, after the terminal node.
+	if !cd.ordered {
+		labels := make([]byte, 0, 256)
+		for ; c != 0; c = cd.nInfos[base^int(c)].sibling {
+			labels = append(labels, c)
+		}
+		sort.Slice(labels, func(i, j int) bool { return labels[i] < labels[j] })
+
+		for _, c := range labels {
+			if !fn(c, base^int(c)) {
+				return
+			}
+		}
+		return
+	}
+
+	for ; c != 0; c = cd.nInfos[base^int(c)].sibling {
+		if !fn(c, base^int(c)) {
+			return
+		}
+	}
+}
+
+// predict calls fn with every node holding a value below `from` in the order
+// of the labels, until fn returns false. It reports whether fn never did.
+func (cd *Cedar) predict(from int, fn func(to int) bool) bool {
+	if cd.Reduced && cd.array[from].baseV >= 0 {
+		return fn(from)
+	}
+
+	ok := true
+	cd.children(from, func(label byte, to int) bool {
+		if label == 0 {
+			ok = fn(to)
+		} else {
+			ok = cd.predict(to, fn)
+		}
+		return ok
+	})
+	return ok
+}
+
+// walk calls fn with every key and value below the node `from` in the order of
+// the labels, `key` is the path leading to `from`. The slice passed to fn is
+// reused, fn must copy it to keep it.
+func (cd *Cedar) walk(from int, key []byte, fn func(key []byte, val int) error) error {
+	n := cd.array[from]
+	if cd.Reduced && n.baseV >= 0 {
+		if n.baseV == ValLimit {
+			return nil
+		}
+		if cd.tail != nil {
+			suffix, val := cd.tail.entry(n.baseV)
+			return fn(append(key, suffix...), val)
+		}
+		return fn(key, n.baseV)
+	}
+
+	var err error
+	cd.children(from, func(label byte, to int) bool {
+		if label != 0 {
+			err = cd.walk(to, append(key, label), fn)
+		} else if val := cd.array[to].baseV; val != ValLimit {
+			err = fn(key, cd.leafValue(val))
+		}
+		return err == nil
+	})
+
+	return err
+}